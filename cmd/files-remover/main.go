@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"iter"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/figurecode/files-remover/conf"
 	"github.com/figurecode/files-remover/remover"
@@ -12,16 +19,60 @@ import (
 )
 
 func main() {
-	var scanDir string
+	var scanDirs listFlag
 	var excDir string
 	var fileNameSep string
+	var matchMode string
 	var isDemo string
 	var filesName []string
+	var protect listFlag
+	var caseInsensitive bool
+	var olderThan, newerThan, timeField string
+	var minSize, maxSize string
+	var fileTypes string
+	var minDepth, maxDepth int
+	var fromFile string
+	var fromStdin, nullSep bool
+	var followSymlinks, stayInRoot bool
+	var oneFileSystem bool
+	var skipFSTypes string
+	var workers int
+	var failFast bool
+	var onError string
+	var format string
+	var templatePath string
+	var sortKey string
+	var reverse bool
 
-	flag.StringVar(&scanDir, "d", "", "Directory to search in. If not specified, the directory from which the program is run will be used")
-	flag.StringVar(&excDir, "e", "", "Excluded subdirectories (comma-separated)")
+	flag.Var(&scanDirs, "d", "Directory to search in, may be a glob like /srv/*/tmp (can be repeated). If not specified, the directory from which the program is run will be used")
+	flag.StringVar(&excDir, "e", "", "Excluded files and directories (comma-separated): names, paths relative to -d, absolute paths or globs with ** like **/vendor/**")
 	flag.StringVar(&isDemo, "m", "true", "Mode: true — demo (dry-run), false — actual deletion (default: true)")
 	flag.StringVar(&fileNameSep, "s", "", "Separator in filename (default: empty). If not specified, search is performed by exact full filename including extension")
+	flag.StringVar(&matchMode, "p", "exact", "Pattern type: exact — full filename or its parts split by -s, glob — shell pattern with *, ?, [...] and {a,b}, regex — Go RE2 regular expression (default: exact)")
+	flag.BoolVar(&caseInsensitive, "i", false, "Case-insensitive matching for all pattern types")
+	flag.StringVar(&olderThan, "older-than", "", "Only files older than an age (7d, 12h, 2w, 1d12h) or a date (2006-01-02, 2006-01-02 15:04)")
+	flag.StringVar(&newerThan, "newer-than", "", "Only files newer than an age (7d, 12h, 2w, 1d12h) or a date (2006-01-02, 2006-01-02 15:04)")
+	flag.StringVar(&timeField, "time-field", "mtime", "File time compared by -older-than and -newer-than: mtime, atime, ctime (default: mtime)")
+	flag.StringVar(&minSize, "min-size", "", "Only files of at least this size: 512, 10K, 1.5M, 2G")
+	flag.StringVar(&maxSize, "max-size", "", "Only files of at most this size: 512, 10K, 1.5M, 2G")
+	flag.StringVar(&fileTypes, "type", "", "Only files of these types (comma-separated): f — regular, l — symlink, broken-link, empty, p — FIFO, s — socket (default: any)")
+	flag.IntVar(&minDepth, "min-depth", 0, "Skip files closer to -d than this depth, files directly in -d have depth 1 (default: 0)")
+	flag.IntVar(&maxDepth, "max-depth", 0, "Do not descend deeper than this depth, 1 — only files directly in -d (default: 0 — no limit)")
+	flag.StringVar(&fromFile, "from-file", "", "Check only the paths listed in this file, one per line, instead of walking -d")
+	flag.BoolVar(&fromStdin, "from-stdin", false, "Check only the paths read from standard input, one per line, instead of walking -d")
+	flag.BoolVar(&nullSep, "null", false, "Paths in -from-file or -from-stdin are separated by NUL, as printed by find -print0")
+	flag.BoolVar(&followSymlinks, "follow-symlinks", false, "Descend into symlinked directories, every directory is scanned once even if links form a loop")
+	flag.BoolVar(&stayInRoot, "stay-in-root", false, "With -follow-symlinks, do not follow links to directories outside -d")
+	flag.BoolVar(&oneFileSystem, "one-file-system", false, "Do not descend into directories on other filesystems than -d, like find -xdev")
+	flag.StringVar(&skipFSTypes, "skip-fs-type", "", "Skip directories on filesystems of these types (comma-separated, globs allowed), e.g. nfs*,fuse,tmpfs,proc. Linux only")
+	flag.IntVar(&workers, "workers", 1, "Number of directories read in parallel, speeds up huge trees; the text and ndjson lists then come in a different order on every run unless -sort is given; -follow-symlinks keeps the scan sequential (default: 1)")
+	flag.BoolVar(&failFast, "fail-fast", false, "Stop at the first file that cannot be removed instead of trying the rest and reporting every failure")
+	flag.StringVar(&onError, "on-error", "abort", "What to do with a directory or file that cannot be read: abort — stop the scan, skip — skip it, warn — skip it and print a warning (default: abort)")
+	flag.StringVar(&format, "format", "text", "Report format: text, json — one document with the count, total bytes and every file, also written after a real deletion, ndjson — one JSON event per line as files are found and removed, csv, tsv — a table of the files with size, mtime, owner and pattern, html — a single page with a sortable table and totals by directory (default: text)")
+	flag.StringVar(&templatePath, "template", "", "Render the report with this Go template file instead of the built-in text, .html and .htm files are parsed as HTML templates")
+	flag.StringVar(&sortKey, "sort", "", "Order of the listed files: path, size, mtime, dir — by directory; the text and ndjson output then waits for the whole scan (default: scan order, path for json, csv, tsv, html and templates)")
+	flag.BoolVar(&reverse, "reverse", false, "Reverse the order, e.g. the largest files first with -sort size")
+	flag.Var(&protect, "protect", "Pattern of files that must never be deleted, wins over all other patterns (can be repeated)")
 
 	if len(os.Args) == 1 || (len(os.Args) == 2 && (os.Args[1] == "-h" || os.Args[1] == "--help")) {
 		fmt.Printf(`
@@ -30,16 +81,93 @@ files-remover — bulk file removal by name pattern
 Usage:
 	files-remover -d <directory> [flags] <pattern1> [pattern2...]
 
+Patterns:
+	A pattern may choose its own matcher with a prefix, overriding -p and -s:
+	exact:NAME   full filename
+	glob:GLOB    shell pattern
+	re:REGEX     regular expression
+	sep=X:NAME   part of the filename split by separator X
+	A leading "!" negates a pattern: matching files are left in place.
+
 Flags:
-	-d string   Directory to search (if omitted, current working directory is used)
-	-e string   Excluded subdirectories (comma-separated)
+	-d string   Directory to search, may be a glob; repeat to scan several
+	            directories (if omitted, current working directory is used)
+	-e string   Excluded files and directories (comma-separated): names,
+	            paths relative to -d, absolute paths or globs with **
 	-m string   Mode: true — demo/dry-run, false — real deletion (default: true)
 	-s string   Filename separator (default: empty)
+	-p string   Pattern type: exact, glob, regex (default: exact)
+	-i          Case-insensitive matching
+	-older-than string
+	            Only files older than an age (7d, 12h, 2w) or a date (2006-01-02)
+	-newer-than string
+	            Only files newer than an age (7d, 12h, 2w) or a date (2006-01-02)
+	-time-field string
+	            File time for age filters: mtime, atime, ctime (default: mtime)
+	-min-size string
+	            Only files of at least this size (512, 10K, 1.5M, 2G)
+	-max-size string
+	            Only files of at most this size (512, 10K, 1.5M, 2G)
+	-type string
+	            Only files of these types (comma-separated): f — regular,
+	            l — symlink, broken-link, empty, p — FIFO, s — socket
+	-min-depth int
+	            Skip files closer to -d than this depth (files in -d have depth 1)
+	-max-depth int
+	            Do not descend deeper than this depth (default: 0 — no limit)
+	-from-file string
+	            Check only the paths listed in this file instead of walking -d
+	-from-stdin Check only the paths read from standard input
+	-null       Listed paths are NUL-separated (find -print0, git ls-files -z)
+	-follow-symlinks
+	            Descend into symlinked directories (each directory once)
+	-stay-in-root
+	            With -follow-symlinks, do not follow links leading outside -d
+	-one-file-system
+	            Do not descend into other filesystems (like find -xdev)
+	-skip-fs-type string
+	            Skip filesystems of these types (comma-separated), e.g.
+	            nfs*,fuse,tmpfs,proc (Linux only)
+	-workers int
+	            Number of directories read in parallel (default: 1)
+	-fail-fast  Stop at the first file that cannot be removed
+	-on-error string
+	            Unreadable directories and files: abort, skip, warn (default: abort)
+	-format string
+	            Report format: text, json, ndjson, csv, tsv, html (default: text)
+	-template string
+	            Render the report with a Go template file (.html — HTML template)
+	-sort string
+	            Order of the listed files: path, size, mtime, dir
+	-reverse    Reverse the order of the listed files
+	-protect string
+	            Pattern of files that must never be deleted (can be repeated)
 
 Examples:
 	files-remover -d /tmp temp-log backup-2024-10-12.tgz
 	files-remover -d /tmp temp-log -s . backup-2024
 	files-remover -d /var/log -m false -e journal access-2024.log
+	files-remover -d /backup -p glob 'backup-*.tar.gz' 'session_????.tmp'
+	files-remover -d /var/log -p regex '^debug-\d{4}\.log$'
+	files-remover -d /data exact:.DS_Store 'glob:cache-*' sep=_:temp
+	files-remover -d /var/log -p glob -protect 'audit-*' '*.log' '!*-keep.log'
+	files-remover -d /backup -i backup.zip
+	files-remover -d /backup -p glob -older-than 30d 'backup-*.zip'
+	files-remover -d /var/crash -p glob -min-size 1G 'core*'
+	files-remover -d /srv -p glob -type broken-link '*'
+	files-remover -d /tmp -p glob -max-depth 1 '*.tmp'
+	files-remover -d /var/log -d '/srv/*/tmp' -d '/home/*/.cache' -p glob '*.tmp'
+	find /tmp -mtime +7 -print0 | files-remover -d /tmp -from-stdin -null -p glob '*'
+	files-remover -d /srv/app -follow-symlinks -stay-in-root -p glob '*.log'
+	files-remover -d / -one-file-system -skip-fs-type 'nfs*,fuse,tmpfs' -p glob 'core.*'
+	files-remover -d /var/lib/ci -workers 16 -p glob '*.o'
+	files-remover -d /home -on-error warn -p glob '*.tmp'
+	files-remover -d /tmp -format json -p glob '*.tmp'
+	files-remover -d /srv -format csv -p glob '*.bak' > plan.csv
+	files-remover -d /var/crash -sort size -reverse -p glob 'core*'
+	files-remover -d /var/log -format html -p glob '*.gz' > cleanup.html
+	files-remover -d /var/cache -template plan.html -p glob '*.cache' > plan.html
+	files-remover -d /tmp -m false -format ndjson -p glob '*.tmp' | jq -c 'select(.event == "failed")'
 `)
 		os.Exit(0)
 	}
@@ -48,40 +176,195 @@ Examples:
 
 	filesName = flag.Args()
 
-	path, err := scanner.ResolvePath(scanDir)
+	roots, err := scanner.ResolveRoots(scanDirs)
 	if err != nil {
 		log.Fatalf("Error scan dir: %v\n", err)
 	}
 
 	cfg, err := conf.New(
-		path,
+		roots,
 		filesName,
 		conf.WithExcludeDir(excDir),
 		conf.WithIsDemo(isDemo),
 		conf.WithFileNameSep(fileNameSep),
+		conf.WithMatchMode(matchMode),
+		conf.WithProtect(protect),
+		conf.WithCaseInsensitive(caseInsensitive),
+		conf.WithOlderThan(olderThan),
+		conf.WithNewerThan(newerThan),
+		conf.WithTimeField(timeField),
+		conf.WithMinSize(minSize),
+		conf.WithMaxSize(maxSize),
+		conf.WithTypes(fileTypes),
+		conf.WithMinDepth(minDepth),
+		conf.WithMaxDepth(maxDepth),
+		conf.WithFromFile(fromFile),
+		conf.WithFromStdin(fromStdin),
+		conf.WithNullSep(nullSep),
+		conf.WithFollowSymlinks(followSymlinks),
+		conf.WithStayInRoot(stayInRoot),
+		conf.WithOneFileSystem(oneFileSystem),
+		conf.WithSkipFSTypes(skipFSTypes),
+		conf.WithWorkers(workers),
+		conf.WithFailFast(failFast),
+		conf.WithOnError(onError),
+		conf.WithFormat(format),
+		conf.WithTemplate(templatePath, remover.TemplateFuncs, remover.TemplateSample()),
+		conf.WithSort(sortKey),
+		conf.WithReverse(reverse),
 	)
 
 	if err != nil {
 		log.Fatalf("Error configuration: %v\n", err)
 	}
 
-	files, err := scanner.ScanDir(cfg)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// A second signal kills the program right away.
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	files := scanner.Scan(ctx, cfg)
+
+	// Structured reports are sorted once collected. Streamed output keeps the
+	// scan order unless a sort is asked for, so memory stays bounded.
+	streamed := cfg.Template == nil && (cfg.Format == conf.FormatText || cfg.Format == conf.FormatNDJSON)
+	if streamed && (cfg.Sort != conf.SortNone || cfg.Reverse) {
+		files = remover.Sorted(files, cfg.Sort, cfg.Reverse)
+	}
+	opts := remover.Options{FailFast: cfg.FailFast}
+
+	var sum remover.Summary
+
+	switch {
+	case cfg.Format == conf.FormatNDJSON:
+		sum, err = writeEvents(ctx, cfg, files, opts)
+	case cfg.Format != conf.FormatText || cfg.Template != nil:
+		sum, err = writeReport(ctx, cfg, files, opts)
+	case cfg.IsDemo:
+		err = remover.DebugStream(files, cfg.OutStream)
+	default:
+		sum, err = remover.ExecuteStream(ctx, files, opts)
+	}
+
+	var removeErr *remover.RemoveError
+
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintln(cfg.ErrStream, "Interrupted, stopped cleanly")
+
+		if !cfg.IsDemo {
+			_ = remover.WriteSummary(sum, cfg.ErrStream)
+		}
+
+		os.Exit(130)
+	}
+
+	if errors.As(err, &removeErr) {
+		fmt.Fprintln(cfg.ErrStream, "Some files could not be removed")
+		_ = remover.WriteSummary(sum, cfg.ErrStream)
+
+		os.Exit(2)
+	}
 
 	if err != nil {
-		fmt.Fprintf(cfg.ErrStream, "Error traversing directory %q: %v\n", cfg.Dir, err)
+		fmt.Fprintf(cfg.ErrStream, "Error: %v\n", err)
 
 		os.Exit(1)
 	}
+}
+
+// writeReport runs the dry run or the removal and writes the whole report in
+// a structured format or with the user template once it is over. A partial
+// report is still written after an interrupt or failed removals.
+func writeReport(ctx context.Context, cfg conf.Config, files iter.Seq2[scanner.FileInfo, error], opts remover.Options) (remover.Summary, error) {
+	rep := remover.NewReport(cfg.IsDemo)
+
+	var sum remover.Summary
+	var err error
 
 	if cfg.IsDemo {
-		err = remover.DebugRemover(files, cfg.OutStream)
+		err = rep.Collect(files)
 	} else {
-		err = remover.Execute(files)
+		opts.OnFile = rep.Record
+		sum, err = remover.ExecuteStream(ctx, files, opts)
+
+		if sum.Interrupted {
+			rep.Interrupt()
+		}
 	}
 
-	if err != nil {
-		fmt.Fprintf(cfg.ErrStream, "Error remove files %v\n", err)
+	var removeErr *remover.RemoveError
 
-		os.Exit(1)
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.As(err, &removeErr) {
+		return sum, err
+	}
+
+	rep.Sort(cfg.Sort, cfg.Reverse)
+
+	var werr error
+
+	switch {
+	case cfg.Template != nil:
+		werr = rep.WriteTemplate(cfg.OutStream, cfg.Template)
+	case cfg.Format == conf.FormatCSV:
+		werr = rep.WriteCSV(cfg.OutStream, ',')
+	case cfg.Format == conf.FormatTSV:
+		werr = rep.WriteCSV(cfg.OutStream, '\t')
+	case cfg.Format == conf.FormatHTML:
+		werr = rep.WriteHTML(cfg.OutStream, time.Now())
+	default:
+		werr = rep.WriteJSON(cfg.OutStream)
+	}
+
+	if werr != nil {
+		return sum, werr
+	}
+
+	return sum, err
+}
+
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+
+	return nil
+}
+
+// writeEvents runs the dry run or the removal and writes every step as an
+// NDJSON event while it happens. A fatal error leaves the stream without the
+// summary event.
+func writeEvents(ctx context.Context, cfg conf.Config, files iter.Seq2[scanner.FileInfo, error], opts remover.Options) (remover.Summary, error) {
+	events := remover.NewEventWriter(cfg.OutStream, cfg.IsDemo)
+	events.Start(cfg.Dirs)
+
+	var sum remover.Summary
+	var err error
+
+	if cfg.IsDemo {
+		err = events.Collect(files)
+	} else {
+		opts.OnFile = events.Record
+		sum, err = remover.ExecuteStream(ctx, events.Stream(files), opts)
 	}
+
+	var removeErr *remover.RemoveError
+
+	interrupted := errors.Is(err, context.Canceled)
+	if err != nil && !interrupted && !errors.As(err, &removeErr) {
+		return sum, err
+	}
+
+	if werr := events.Finish(interrupted); werr != nil {
+		return sum, werr
+	}
+
+	return sum, err
 }
@@ -0,0 +1,105 @@
+package conf
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TimeField int
+
+const (
+	ModTime TimeField = iota
+	AccessTime
+	ChangeTime
+)
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+var ageUnits = map[byte]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+// timeLimit is either an age relative to the current time or an absolute date.
+type timeLimit struct {
+	age time.Duration
+	at  time.Time
+}
+
+func (l timeLimit) resolve(now time.Time) time.Time {
+	if !l.at.IsZero() {
+		return l.at
+	}
+
+	return now.Add(-l.age)
+}
+
+// parseTimeLimit accepts an age like "7d", "12h", "2w" or "1d12h", or an
+// absolute date like "2025-01-31" or "2025-01-31 12:00", read in local time.
+func parseTimeLimit(v string) (timeLimit, error) {
+	v = strings.TrimSpace(v)
+
+	if age, ok := parseAge(v); ok {
+		return timeLimit{age: age}, nil
+	}
+
+	for _, layout := range dateLayouts {
+		if at, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			return timeLimit{at: at}, nil
+		}
+	}
+
+	return timeLimit{}, fmt.Errorf("%w %q: expected an age like 7d, 12h, 2w or a date like 2006-01-02", errMessInvalidTimeLimit, v)
+}
+
+func parseAge(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+
+	var age time.Duration
+
+	for v != "" {
+		i := 0
+		for i < len(v) && v[i] >= '0' && v[i] <= '9' {
+			i++
+		}
+
+		if i == 0 || i == len(v) {
+			return 0, false
+		}
+
+		unit, ok := ageUnits[v[i]]
+		if !ok {
+			return 0, false
+		}
+
+		n, err := strconv.ParseInt(v[:i], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+
+		// An age past the range of time.Duration would wrap around to a
+		// negative one and select every file.
+		if n > math.MaxInt64/int64(unit) || age > math.MaxInt64-time.Duration(n)*unit {
+			return 0, false
+		}
+
+		age += time.Duration(n) * unit
+		v = v[i+1:]
+	}
+
+	return age, true
+}
@@ -0,0 +1,55 @@
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseTimeLimit(t *testing.T) {
+	tests := []struct {
+		got  string
+		want timeLimit
+	}{
+		{got: "30s", want: timeLimit{age: 30 * time.Second}},
+		{got: "15m", want: timeLimit{age: 15 * time.Minute}},
+		{got: "12h", want: timeLimit{age: 12 * time.Hour}},
+		{got: "7d", want: timeLimit{age: 7 * 24 * time.Hour}},
+		{got: "2w", want: timeLimit{age: 14 * 24 * time.Hour}},
+		{got: "1d12h", want: timeLimit{age: 36 * time.Hour}},
+		{got: " 0d ", want: timeLimit{}},
+		{got: "15000w", want: timeLimit{age: 15000 * 7 * 24 * time.Hour}},
+		{got: "2025-01-31", want: timeLimit{at: time.Date(2025, 1, 31, 0, 0, 0, 0, time.Local)}},
+		{got: "2025-01-31 12:30", want: timeLimit{at: time.Date(2025, 1, 31, 12, 30, 0, 0, time.Local)}},
+		{got: "2025-01-31T12:30:15", want: timeLimit{at: time.Date(2025, 1, 31, 12, 30, 15, 0, time.Local)}},
+		{got: "2025-01-31T12:30:15Z", want: timeLimit{at: time.Date(2025, 1, 31, 12, 30, 15, 0, time.UTC)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.got, func(t *testing.T) {
+			got, err := parseTimeLimit(tt.got)
+
+			assert.NoError(t, err)
+			assert.True(t, tt.want.at.Equal(got.at), "got %v, want %v", got.at, tt.want.at)
+			assert.Equal(t, tt.want.age, got.age)
+		})
+	}
+
+	t.Run("invalid limits", func(t *testing.T) {
+		for _, v := range []string{"", "7", "d", "7y", "-7d", "7d1", "1.5d", "31.01.2025", "2025-13-01",
+			"20000w", "9223372036854775807s", "9223372036854775808s", "15000w15000w", "106751d106751d"} {
+			_, err := parseTimeLimit(v)
+
+			assert.ErrorIs(t, err, errMessInvalidTimeLimit, v)
+		}
+	})
+}
+
+func Test_timeLimitResolve(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, now.Add(-7*24*time.Hour), timeLimit{age: 7 * 24 * time.Hour}.resolve(now))
+	assert.Equal(t, at, timeLimit{at: at}.resolve(now))
+}
@@ -2,29 +2,97 @@ package conf
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 )
 
 var errMessErrStreamIsNil = errors.New("errStream cannot be nil")
 var errMessOutStreamIsNil = errors.New("outStream cannot be nil")
+var errMessInStreamIsNil = errors.New("inStream cannot be nil")
 var errMessDirIsNotSpecified = errors.New("search directory not specified")
 var errMessFileListIsEmpty = errors.New("the file name list cannot be empty")
+var errMessUnknownMatchMode = errors.New("unknown match mode")
+var errMessInvalidPattern = errors.New("invalid pattern")
+var errMessNoPositivePattern = errors.New("at least one pattern without \"!\" is required")
+var errMessNegatedProtect = errors.New("protect patterns cannot be negated")
+var errMessInvalidTimeLimit = errors.New("invalid time limit")
+var errMessUnknownTimeField = errors.New("unknown time field")
+var errMessEmptyTimeRange = errors.New("newer-than limit must be earlier than older-than limit")
+var errMessClockIsNil = errors.New("clock cannot be nil")
+var errMessInvalidSize = errors.New("invalid size")
+var errMessEmptySizeRange = errors.New("min-size cannot be greater than max-size")
+var errMessUnknownFileType = errors.New("unknown file type")
+var errMessInvalidExclusion = errors.New("invalid exclusion")
+var errMessNegativeDepth = errors.New("depth cannot be negative")
+var errMessEmptyDepthRange = errors.New("min-depth cannot be greater than max-depth")
+var errMessListSourceConflict = errors.New("from-file and from-stdin cannot be used together")
+var errMessStayInRootWithoutFollow = errors.New("stay-in-root requires follow-symlinks")
+var errMessInvalidFSType = errors.New("invalid filesystem type")
+var errMessNegativeWorkers = errors.New("workers cannot be negative")
+var errMessUnknownErrorPolicy = errors.New("unknown error policy")
+var errMessUnknownFormat = errors.New("unknown output format")
+var errMessUnknownSortKey = errors.New("unknown sort key")
+var errMessInvalidTemplate = errors.New("invalid report template")
+var errMessTemplateWithFormat = errors.New("template can only be used with the text format")
 
 type Config struct {
-	Dir                  string
-	FilesName            map[string]bool
+	Dirs                 []string
+	FilesName            []Rule
+	Protect              []Rule
 	ExcDirs              []string
+	Exclusions           []Exclusion
 	FileNameSep          string
+	MatchMode            MatchMode
+	CaseInsensitive      bool
+	OlderThan, NewerThan time.Time
+	TimeField            TimeField
+	Now                  func() time.Time
+	MinSize, MaxSize     int64
+	Types                FileType
+	MinDepth, MaxDepth   int
+	FromFile             string
+	FromStdin, NullSep   bool
+	FollowSymlinks       bool
+	StayInRoot           bool
+	OneFileSystem        bool
+	SkipFSTypes          []string
+	Workers              int
+	FailFast             bool
+	OnError              ErrorPolicy
+	Format               Format
+	Template             Template
+	Sort                 SortKey
+	Reverse              bool
 	IsDemo               bool
 	ErrStream, OutStream io.Writer
+	InStream             io.Reader
+
+	protect              []string
+	olderThan, newerThan *timeLimit
 }
 
 type Option func(*Config) error
 
+// ErrorPolicy tells the scan what to do with a directory or file it cannot
+// read.
+type ErrorPolicy int
+
+const (
+	// ErrorAbort stops the scan with the error.
+	ErrorAbort ErrorPolicy = iota
+	// ErrorSkip skips the path and records it in the scan result.
+	ErrorSkip
+	// ErrorWarn also prints a warning to ErrStream.
+	ErrorWarn
+)
+
 func (c Config) validate() error {
-	if c.Dir == "" {
+	if len(c.Dirs) == 0 {
 		return errMessDirIsNotSpecified
 	}
 
@@ -32,9 +100,45 @@ func (c Config) validate() error {
 		return errMessFileListIsEmpty
 	}
 
+	if !slices.ContainsFunc(c.FilesName, func(r Rule) bool { return !r.Negate }) {
+		return errMessNoPositivePattern
+	}
+
+	if !c.OlderThan.IsZero() && !c.NewerThan.IsZero() && !c.NewerThan.Before(c.OlderThan) {
+		return errMessEmptyTimeRange
+	}
+
+	if c.MaxSize >= 0 && c.MinSize > c.MaxSize {
+		return errMessEmptySizeRange
+	}
+
+	if c.MaxDepth > 0 && c.MinDepth > c.MaxDepth {
+		return errMessEmptyDepthRange
+	}
+
+	if c.FromFile != "" && c.FromStdin {
+		return errMessListSourceConflict
+	}
+
+	if c.StayInRoot && !c.FollowSymlinks {
+		return errMessStayInRootWithoutFollow
+	}
+
+	if c.Template != nil && c.Format != FormatText {
+		return errMessTemplateWithFormat
+	}
+
 	return nil
 }
 
+func (c Config) now() time.Time {
+	if c.Now == nil {
+		return time.Now()
+	}
+
+	return c.Now()
+}
+
 func WithErrStream(errStream io.Writer) Option {
 	return func(c *Config) error {
 		if errStream == nil {
@@ -59,6 +163,18 @@ func WithOutStream(outStream io.Writer) Option {
 	}
 }
 
+func WithInStream(inStream io.Reader) Option {
+	return func(c *Config) error {
+		if inStream == nil {
+			return errMessInStreamIsNil
+		}
+
+		c.InStream = inStream
+
+		return nil
+	}
+}
+
 func WithExcludeDir(excDir string) Option {
 	return func(c *Config) error {
 		if excDir != "" {
@@ -81,6 +197,387 @@ func WithFileNameSep(sep string) Option {
 	}
 }
 
+func WithMatchMode(mode string) Option {
+	return func(c *Config) error {
+		switch strings.TrimSpace(mode) {
+		case "", "exact":
+			c.MatchMode = MatchExact
+		case "glob":
+			c.MatchMode = MatchGlob
+		case "regex":
+			c.MatchMode = MatchRegexp
+		default:
+			return fmt.Errorf("%w: %q", errMessUnknownMatchMode, mode)
+		}
+
+		return nil
+	}
+}
+
+func WithCaseInsensitive(enabled bool) Option {
+	return func(c *Config) error {
+		c.CaseInsensitive = enabled
+
+		return nil
+	}
+}
+
+func WithProtect(patterns []string) Option {
+	return func(c *Config) error {
+		for _, p := range patterns {
+			if p = strings.TrimSpace(p); p != "" {
+				c.protect = append(c.protect, p)
+			}
+		}
+
+		return nil
+	}
+}
+
+func WithOlderThan(limit string) Option {
+	return func(c *Config) error {
+		if limit == "" {
+			return nil
+		}
+
+		l, err := parseTimeLimit(limit)
+		if err != nil {
+			return err
+		}
+
+		c.olderThan = &l
+
+		return nil
+	}
+}
+
+func WithNewerThan(limit string) Option {
+	return func(c *Config) error {
+		if limit == "" {
+			return nil
+		}
+
+		l, err := parseTimeLimit(limit)
+		if err != nil {
+			return err
+		}
+
+		c.newerThan = &l
+
+		return nil
+	}
+}
+
+func WithTimeField(field string) Option {
+	return func(c *Config) error {
+		switch strings.TrimSpace(field) {
+		case "", "mtime":
+			c.TimeField = ModTime
+		case "atime":
+			c.TimeField = AccessTime
+		case "ctime":
+			c.TimeField = ChangeTime
+		default:
+			return fmt.Errorf("%w: %q", errMessUnknownTimeField, field)
+		}
+
+		return nil
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(c *Config) error {
+		if now == nil {
+			return errMessClockIsNil
+		}
+
+		c.Now = now
+
+		return nil
+	}
+}
+
+func WithMinSize(size string) Option {
+	return func(c *Config) error {
+		if size == "" {
+			return nil
+		}
+
+		n, err := parseSize(size)
+		if err != nil {
+			return err
+		}
+
+		c.MinSize = n
+
+		return nil
+	}
+}
+
+// WithMaxSize limits the size of matched files. Without it MaxSize is
+// negative, which means no limit.
+func WithMaxSize(size string) Option {
+	return func(c *Config) error {
+		if size == "" {
+			return nil
+		}
+
+		n, err := parseSize(size)
+		if err != nil {
+			return err
+		}
+
+		c.MaxSize = n
+
+		return nil
+	}
+}
+
+func WithTypes(list string) Option {
+	return func(c *Config) error {
+		types, err := parseFileTypes(list)
+		if err != nil {
+			return err
+		}
+
+		c.Types = types
+
+		return nil
+	}
+}
+
+// WithMinDepth skips files closer to their root than depth. Files directly in
+// a root have depth 1.
+func WithMinDepth(depth int) Option {
+	return func(c *Config) error {
+		if depth < 0 {
+			return errMessNegativeDepth
+		}
+
+		c.MinDepth = depth
+
+		return nil
+	}
+}
+
+// WithMaxDepth stops the traversal at depth. Zero means no limit.
+func WithMaxDepth(depth int) Option {
+	return func(c *Config) error {
+		if depth < 0 {
+			return errMessNegativeDepth
+		}
+
+		c.MaxDepth = depth
+
+		return nil
+	}
+}
+
+// WithFromFile reads candidate paths from a file instead of walking the
+// search directories.
+func WithFromFile(path string) Option {
+	return func(c *Config) error {
+		c.FromFile = strings.TrimSpace(path)
+
+		return nil
+	}
+}
+
+// WithFromStdin reads candidate paths from InStream instead of walking the
+// search directories.
+func WithFromStdin(enabled bool) Option {
+	return func(c *Config) error {
+		c.FromStdin = enabled
+
+		return nil
+	}
+}
+
+// WithNullSep makes candidate path lists NUL-delimited, as produced by
+// find -print0 or git ls-files -z.
+func WithNullSep(enabled bool) Option {
+	return func(c *Config) error {
+		c.NullSep = enabled
+
+		return nil
+	}
+}
+
+// WithFollowSymlinks makes the scan descend into symlinked directories. Every
+// directory is walked once, however many links lead to it.
+func WithFollowSymlinks(enabled bool) Option {
+	return func(c *Config) error {
+		c.FollowSymlinks = enabled
+
+		return nil
+	}
+}
+
+// WithStayInRoot keeps WithFollowSymlinks from following links to
+// directories outside the root being scanned.
+func WithStayInRoot(enabled bool) Option {
+	return func(c *Config) error {
+		c.StayInRoot = enabled
+
+		return nil
+	}
+}
+
+// WithOneFileSystem keeps the scan on the device of the root being scanned,
+// like find -xdev.
+func WithOneFileSystem(enabled bool) Option {
+	return func(c *Config) error {
+		c.OneFileSystem = enabled
+
+		return nil
+	}
+}
+
+// WithSkipFSTypes skips directories on filesystems of the listed types
+// (comma-separated, globs allowed). A type also matches its subtypes, so
+// "fuse" covers "fuse.sshfs".
+func WithSkipFSTypes(list string) Option {
+	return func(c *Config) error {
+		for _, t := range strings.Split(list, ",") {
+			t = strings.ToLower(strings.TrimSpace(t))
+
+			pattern := negateClasses(t)
+			if pattern == "" || slices.Contains(c.SkipFSTypes, pattern) {
+				continue
+			}
+
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%w: %q", errMessInvalidFSType, t)
+			}
+
+			c.SkipFSTypes = append(c.SkipFSTypes, pattern)
+		}
+
+		return nil
+	}
+}
+
+// WithWorkers sets how many directories are read in parallel. Zero and one
+// keep the scan sequential.
+func WithWorkers(n int) Option {
+	return func(c *Config) error {
+		if n < 0 {
+			return errMessNegativeWorkers
+		}
+
+		c.Workers = n
+
+		return nil
+	}
+}
+
+// WithFailFast stops the removal at the first file that cannot be removed.
+// Without it the removal goes on and reports every failure at the end.
+func WithFailFast(enabled bool) Option {
+	return func(c *Config) error {
+		c.FailFast = enabled
+
+		return nil
+	}
+}
+
+// Format is the output format of the report.
+type Format int
+
+const (
+	FormatText Format = iota
+	FormatJSON
+	FormatNDJSON
+	FormatCSV
+	FormatTSV
+	FormatHTML
+)
+
+func WithFormat(format string) Option {
+	return func(c *Config) error {
+		switch strings.TrimSpace(format) {
+		case "", "text":
+			c.Format = FormatText
+		case "json":
+			c.Format = FormatJSON
+		case "ndjson":
+			c.Format = FormatNDJSON
+		case "csv":
+			c.Format = FormatCSV
+		case "tsv":
+			c.Format = FormatTSV
+		case "html":
+			c.Format = FormatHTML
+		default:
+			return fmt.Errorf("%w: %q", errMessUnknownFormat, format)
+		}
+
+		return nil
+	}
+}
+
+// SortKey orders the files of the report.
+type SortKey int
+
+const (
+	// SortNone keeps the scan order in streamed output, structured reports are
+	// sorted by path then.
+	SortNone SortKey = iota
+	SortPath
+	SortSize
+	SortMTime
+	// SortDir groups the files by their directory.
+	SortDir
+)
+
+func WithSort(key string) Option {
+	return func(c *Config) error {
+		switch strings.TrimSpace(key) {
+		case "":
+			c.Sort = SortNone
+		case "path":
+			c.Sort = SortPath
+		case "size":
+			c.Sort = SortSize
+		case "mtime":
+			c.Sort = SortMTime
+		case "dir":
+			c.Sort = SortDir
+		default:
+			return fmt.Errorf("%w: %q", errMessUnknownSortKey, key)
+		}
+
+		return nil
+	}
+}
+
+// WithReverse reverses the sort order, so --sort size lists the largest
+// files first.
+func WithReverse(enabled bool) Option {
+	return func(c *Config) error {
+		c.Reverse = enabled
+
+		return nil
+	}
+}
+
+func WithOnError(policy string) Option {
+	return func(c *Config) error {
+		switch strings.TrimSpace(policy) {
+		case "", "abort":
+			c.OnError = ErrorAbort
+		case "skip":
+			c.OnError = ErrorSkip
+		case "warn":
+			c.OnError = ErrorWarn
+		default:
+			return fmt.Errorf("%w: %q", errMessUnknownErrorPolicy, policy)
+		}
+
+		return nil
+	}
+}
+
 func WithIsDemo(isDemo string) Option {
 	return func(c *Config) error {
 		c.IsDemo = isDemo == "true"
@@ -89,31 +586,79 @@ func WithIsDemo(isDemo string) Option {
 	}
 }
 
-func New(dir string, fNames []string, opts ...Option) (Config, error) {
+func New(dirs []string, fNames []string, opts ...Option) (Config, error) {
 	c := Config{
-		Dir:         strings.TrimSpace(dir),
-		FilesName:   make(map[string]bool, 0),
+		Dirs:        make([]string, 0, len(dirs)),
+		FilesName:   make([]Rule, 0),
 		ExcDirs:     make([]string, 0),
 		FileNameSep: "",
+		MaxSize:     -1,
 		IsDemo:      true,
 		ErrStream:   os.Stderr,
 		OutStream:   os.Stdout,
+		InStream:    os.Stdin,
+	}
+
+	for _, d := range dirs {
+		if d = strings.TrimSpace(d); d != "" && !slices.Contains(c.Dirs, d) {
+			c.Dirs = append(c.Dirs, d)
+		}
+	}
+
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return Config{}, err
+		}
 	}
 
 	for _, v := range fNames {
-		c.FilesName[v] = true
+		r, err := parseRule(v, c.MatchMode, c.FileNameSep, c.CaseInsensitive)
+		if err != nil {
+			return Config{}, err
+		}
+
+		if !slices.ContainsFunc(c.FilesName, r.equal) {
+			c.FilesName = append(c.FilesName, r)
+		}
+	}
+
+	for _, v := range c.protect {
+		r, err := parseRule(v, c.MatchMode, c.FileNameSep, c.CaseInsensitive)
+		if err != nil {
+			return Config{}, err
+		}
+
+		if r.Negate {
+			return Config{}, fmt.Errorf("%w: %q", errMessNegatedProtect, v)
+		}
+
+		if !slices.ContainsFunc(c.Protect, r.equal) {
+			c.Protect = append(c.Protect, r)
+		}
+	}
+
+	c.protect = nil
+
+	now := c.now()
+
+	if c.olderThan != nil {
+		c.OlderThan = c.olderThan.resolve(now)
 	}
 
+	if c.newerThan != nil {
+		c.NewerThan = c.newerThan.resolve(now)
+	}
+
+	c.olderThan, c.newerThan = nil, nil
+
 	err := c.validate()
 
 	if err != nil {
 		return Config{}, err
 	}
 
-	for _, opt := range opts {
-		if err := opt(&c); err != nil {
-			return Config{}, err
-		}
+	if err := c.compileExclusions(); err != nil {
+		return Config{}, err
 	}
 
 	return c, nil
@@ -3,7 +3,10 @@ package conf
 import (
 	"bytes"
 	"os"
+	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -11,27 +14,29 @@ import (
 func TestNew(t *testing.T) {
 	t.Run("correct config", func(t *testing.T) {
 		want := Config{
-			FilesName:   map[string]bool{"file1": true, "file2": true},
-			Dir:         "/",
+			FilesName:   []Rule{{Mode: MatchExact, Pattern: "file1"}, {Mode: MatchExact, Pattern: "file2"}},
+			Dirs:        []string{"/"},
 			IsDemo:      true,
 			ExcDirs:     make([]string, 0),
 			OutStream:   os.Stdout,
 			ErrStream:   os.Stderr,
+			InStream:    os.Stdin,
 			FileNameSep: "",
+			MaxSize:     -1,
 		}
 
 		filesName := []string{"file1", "file2"}
 		cfg, err := New(
-			"/",
+			[]string{"/"},
 			filesName,
 		)
 
 		assert.NoError(t, err)
-		assert.Equal(t, "/", cfg.Dir)
+		assert.Equal(t, []string{"/"}, cfg.Dirs)
 
 		for i := range filesName {
-			if _, ok := cfg.FilesName[filesName[i]]; !ok {
-				t.Errorf("The files map does not contain the entire data set. Absent %s, list %q, got map: %v\n", filesName[i], filesName, cfg.FilesName)
+			if !slices.ContainsFunc(cfg.FilesName, func(r Rule) bool { return r.Pattern == filesName[i] }) {
+				t.Errorf("The rules list does not contain the entire data set. Absent %s, list %q, got rules: %v\n", filesName[i], filesName, cfg.FilesName)
 
 				break
 			}
@@ -41,20 +46,20 @@ func TestNew(t *testing.T) {
 	})
 
 	t.Run("not correct config", func(t *testing.T) {
-		cfg, err := New("", nil)
+		cfg, err := New([]string{""}, nil)
 
 		assert.Error(t, err)
 		assert.Empty(t, cfg)
 	})
 
 	t.Run("check empty Dir", func(t *testing.T) {
-		_, err := New("", []string{"file1", "file2"})
+		_, err := New([]string{""}, []string{"file1", "file2"})
 
 		assert.ErrorIs(t, err, errMessDirIsNotSpecified)
 	})
 
 	t.Run("check empty Files", func(t *testing.T) {
-		_, err := New("/", []string{})
+		_, err := New([]string{"/"}, []string{})
 
 		assert.ErrorIs(t, err, errMessFileListIsEmpty)
 	})
@@ -164,3 +169,520 @@ func TestWithIsDemo(t *testing.T) {
 		assert.Equal(t, false, cfg.IsDemo)
 	})
 }
+
+func TestWithMatchMode(t *testing.T) {
+	tests := []struct {
+		mode string
+		want MatchMode
+	}{
+		{mode: "", want: MatchExact},
+		{mode: "exact", want: MatchExact},
+		{mode: "glob", want: MatchGlob},
+		{mode: "regex", want: MatchRegexp},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{}
+		err := WithMatchMode(tt.mode)(cfg)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, cfg.MatchMode)
+	}
+
+	t.Run("unknown mode", func(t *testing.T) {
+		cfg := &Config{}
+		err := WithMatchMode("fuzzy")(cfg)
+
+		assert.ErrorIs(t, err, errMessUnknownMatchMode)
+	})
+}
+
+func TestNewGlob(t *testing.T) {
+	t.Run("compile globs", func(t *testing.T) {
+		cfg, err := New(
+			[]string{"/"},
+			[]string{"backup-*.{tar.gz,zip}", "session_????.tmp", "backup-*.{tar.gz,zip}"},
+			WithMatchMode("glob"),
+		)
+
+		assert.NoError(t, err)
+		assert.Len(t, cfg.FilesName, 2)
+		assert.Equal(t, []string{"backup-*.tar.gz", "backup-*.zip"}, cfg.FilesName[0].Globs)
+		assert.Equal(t, []string{"session_????.tmp"}, cfg.FilesName[1].Globs)
+	})
+
+	t.Run("invalid glob", func(t *testing.T) {
+		_, err := New([]string{"/"}, []string{"log-[a-"}, WithMatchMode("glob"))
+
+		assert.ErrorIs(t, err, errMessInvalidPattern)
+	})
+}
+
+func TestNewRegexp(t *testing.T) {
+	t.Run("compile regexps", func(t *testing.T) {
+		cfg, err := New([]string{"/"}, []string{`^debug-\d{4}\.log$`, `^session_`}, WithMatchMode("regex"))
+
+		assert.NoError(t, err)
+		assert.Len(t, cfg.FilesName, 2)
+		assert.True(t, cfg.FilesName[0].Regexp.MatchString("debug-2025.log"))
+		assert.False(t, cfg.FilesName[0].Regexp.MatchString("debug-25.log"))
+	})
+
+	t.Run("invalid regexp fails fast", func(t *testing.T) {
+		_, err := New([]string{"/"}, []string{`^debug-(\d+\.log$`}, WithMatchMode("regex"))
+
+		assert.ErrorIs(t, err, errMessInvalidPattern)
+		assert.ErrorContains(t, err, `^debug-(\d+\.log$`)
+	})
+}
+
+func TestNewRules(t *testing.T) {
+	cfg, err := New(
+		[]string{"/"},
+		[]string{"exact:.DS_Store", "glob:cache-*", "sep=_:temp", "temp", "temp"},
+		WithFileNameSep("-"),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Mode: MatchExact, Pattern: ".DS_Store"},
+		{Mode: MatchGlob, Pattern: "cache-*", Globs: []string{"cache-*"}},
+		{Mode: MatchExact, Pattern: "temp", Sep: "_"},
+		{Mode: MatchExact, Pattern: "temp", Sep: "-"},
+	}, cfg.FilesName)
+}
+
+func TestWithProtect(t *testing.T) {
+	cfg := &Config{}
+	err := WithProtect([]string{"audit-*", " ", " *-keep.log "})(cfg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"audit-*", "*-keep.log"}, cfg.protect)
+}
+
+func TestNewNegateAndProtect(t *testing.T) {
+	t.Run("compile protect rules with default mode", func(t *testing.T) {
+		cfg, err := New(
+			[]string{"/"},
+			[]string{"*.log", "!*-keep.log"},
+			WithProtect([]string{"audit-*", "exact:audit.log"}),
+			WithMatchMode("glob"),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Rule{
+			{Mode: MatchGlob, Pattern: "*.log", Globs: []string{"*.log"}},
+			{Mode: MatchGlob, Pattern: "*-keep.log", Negate: true, Globs: []string{"*-keep.log"}},
+		}, cfg.FilesName)
+		assert.Equal(t, []Rule{
+			{Mode: MatchGlob, Pattern: "audit-*", Globs: []string{"audit-*"}},
+			{Mode: MatchExact, Pattern: "audit.log"},
+		}, cfg.Protect)
+		assert.Nil(t, cfg.protect)
+	})
+
+	t.Run("only negated patterns", func(t *testing.T) {
+		_, err := New([]string{"/"}, []string{"!*.log"}, WithMatchMode("glob"))
+
+		assert.ErrorIs(t, err, errMessNoPositivePattern)
+	})
+
+	t.Run("negated protect pattern", func(t *testing.T) {
+		_, err := New([]string{"/"}, []string{"*.log"}, WithProtect([]string{"!audit-*"}))
+
+		assert.ErrorIs(t, err, errMessNegatedProtect)
+	})
+}
+
+func TestWithCaseInsensitive(t *testing.T) {
+	cfg, err := New([]string{"/"}, []string{"Backup.ZIP", "glob:*.LOG"}, WithCaseInsensitive(true))
+
+	assert.NoError(t, err)
+	assert.True(t, cfg.CaseInsensitive)
+
+	for _, r := range cfg.FilesName {
+		assert.True(t, r.Fold, r.Pattern)
+	}
+}
+
+func TestNewTimeLimits(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	t.Run("resolve limits with the injected clock", func(t *testing.T) {
+		cfg, err := New(
+			[]string{"/"},
+			[]string{"file1"},
+			WithOlderThan("7d"),
+			WithNewerThan("2w"),
+			WithTimeField("atime"),
+			WithClock(clock),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, now.Add(-7*24*time.Hour), cfg.OlderThan)
+		assert.Equal(t, now.Add(-14*24*time.Hour), cfg.NewerThan)
+		assert.Equal(t, AccessTime, cfg.TimeField)
+		assert.Nil(t, cfg.olderThan)
+		assert.Nil(t, cfg.newerThan)
+	})
+
+	t.Run("empty time range", func(t *testing.T) {
+		_, err := New([]string{"/"}, []string{"file1"}, WithOlderThan("2w"), WithNewerThan("7d"), WithClock(clock))
+
+		assert.ErrorIs(t, err, errMessEmptyTimeRange)
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		_, err := New([]string{"/"}, []string{"file1"}, WithOlderThan("last week"))
+
+		assert.ErrorIs(t, err, errMessInvalidTimeLimit)
+	})
+}
+
+func TestWithTimeField(t *testing.T) {
+	tests := []struct {
+		field string
+		want  TimeField
+	}{
+		{field: "", want: ModTime},
+		{field: "mtime", want: ModTime},
+		{field: "atime", want: AccessTime},
+		{field: "ctime", want: ChangeTime},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{}
+		err := WithTimeField(tt.field)(cfg)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, cfg.TimeField)
+	}
+
+	t.Run("unknown field", func(t *testing.T) {
+		err := WithTimeField("btime")(&Config{})
+
+		assert.ErrorIs(t, err, errMessUnknownTimeField)
+	})
+}
+
+func TestWithClock(t *testing.T) {
+	t.Run("set nil clock", func(t *testing.T) {
+		err := WithClock(nil)(&Config{})
+
+		assert.ErrorIs(t, err, errMessClockIsNil)
+	})
+}
+
+func TestNewSizeLimits(t *testing.T) {
+	t.Run("no limits by default", func(t *testing.T) {
+		cfg, err := New([]string{"/"}, []string{"file1"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), cfg.MinSize)
+		assert.Equal(t, int64(-1), cfg.MaxSize)
+	})
+
+	t.Run("set limits", func(t *testing.T) {
+		cfg, err := New([]string{"/"}, []string{"file1"}, WithMinSize("10K"), WithMaxSize("1.5M"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10240), cfg.MinSize)
+		assert.Equal(t, int64(1572864), cfg.MaxSize)
+	})
+
+	t.Run("zero max size", func(t *testing.T) {
+		cfg, err := New([]string{"/"}, []string{"file1"}, WithMaxSize("0"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), cfg.MaxSize)
+	})
+
+	t.Run("empty size range", func(t *testing.T) {
+		_, err := New([]string{"/"}, []string{"file1"}, WithMinSize("2G"), WithMaxSize("1G"))
+
+		assert.ErrorIs(t, err, errMessEmptySizeRange)
+	})
+
+	t.Run("invalid size", func(t *testing.T) {
+		_, err := New([]string{"/"}, []string{"file1"}, WithMinSize("ten"))
+
+		assert.ErrorIs(t, err, errMessInvalidSize)
+	})
+}
+
+func TestWithTypes(t *testing.T) {
+	t.Run("set Types", func(t *testing.T) {
+		cfg := &Config{}
+		err := WithTypes("f,broken-link")(cfg)
+
+		assert.NoError(t, err)
+		assert.Equal(t, TypeRegular|TypeBrokenLink, cfg.Types)
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		err := WithTypes("x")(&Config{})
+
+		assert.ErrorIs(t, err, errMessUnknownFileType)
+	})
+}
+
+func TestNewDepthLimits(t *testing.T) {
+	t.Run("set limits", func(t *testing.T) {
+		cfg, err := New([]string{"/"}, []string{"file1"}, WithMinDepth(2), WithMaxDepth(3))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, cfg.MinDepth)
+		assert.Equal(t, 3, cfg.MaxDepth)
+	})
+
+	t.Run("negative depth", func(t *testing.T) {
+		_, err := New([]string{"/"}, []string{"file1"}, WithMaxDepth(-1))
+
+		assert.ErrorIs(t, err, errMessNegativeDepth)
+	})
+
+	t.Run("empty depth range", func(t *testing.T) {
+		_, err := New([]string{"/"}, []string{"file1"}, WithMinDepth(3), WithMaxDepth(2))
+
+		assert.ErrorIs(t, err, errMessEmptyDepthRange)
+	})
+}
+
+func TestWithInStream(t *testing.T) {
+	t.Run("set InStream", func(t *testing.T) {
+		cfg := &Config{}
+		err := WithInStream(&bytes.Buffer{})(cfg)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &bytes.Buffer{}, cfg.InStream)
+	})
+
+	t.Run("set nil InStream", func(t *testing.T) {
+		err := WithInStream(nil)(&Config{})
+
+		assert.ErrorIs(t, err, errMessInStreamIsNil)
+	})
+}
+
+func TestNewPathList(t *testing.T) {
+	t.Run("read from file", func(t *testing.T) {
+		cfg, err := New([]string{"/"}, []string{"file1"}, WithFromFile(" list.txt "), WithNullSep(true))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "list.txt", cfg.FromFile)
+		assert.True(t, cfg.NullSep)
+	})
+
+	t.Run("file and stdin together", func(t *testing.T) {
+		_, err := New([]string{"/"}, []string{"file1"}, WithFromFile("list.txt"), WithFromStdin(true))
+
+		assert.ErrorIs(t, err, errMessListSourceConflict)
+	})
+}
+
+func TestNewFollowSymlinks(t *testing.T) {
+	t.Run("follow inside root", func(t *testing.T) {
+		cfg, err := New([]string{"/"}, []string{"file1"}, WithFollowSymlinks(true), WithStayInRoot(true))
+
+		assert.NoError(t, err)
+		assert.True(t, cfg.FollowSymlinks)
+		assert.True(t, cfg.StayInRoot)
+	})
+
+	t.Run("stay in root without follow", func(t *testing.T) {
+		_, err := New([]string{"/"}, []string{"file1"}, WithStayInRoot(true))
+
+		assert.ErrorIs(t, err, errMessStayInRootWithoutFollow)
+	})
+}
+
+func TestNewFileSystems(t *testing.T) {
+	t.Run("set options", func(t *testing.T) {
+		cfg, err := New([]string{"/"}, []string{"file1"}, WithOneFileSystem(true), WithSkipFSTypes(" NFS*, fuse,,tmpfs,fuse,[!e]xt4 "))
+
+		assert.NoError(t, err)
+		assert.True(t, cfg.OneFileSystem)
+		assert.Equal(t, []string{"nfs*", "fuse", "tmpfs", "[^e]xt4"}, cfg.SkipFSTypes)
+	})
+
+	t.Run("invalid type pattern", func(t *testing.T) {
+		_, err := New([]string{"/"}, []string{"file1"}, WithSkipFSTypes("nfs["))
+
+		assert.ErrorIs(t, err, errMessInvalidFSType)
+	})
+}
+
+func TestWithWorkers(t *testing.T) {
+	t.Run("set workers", func(t *testing.T) {
+		cfg, err := New([]string{"/"}, []string{"file1"}, WithWorkers(8))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 8, cfg.Workers)
+	})
+
+	t.Run("negative workers", func(t *testing.T) {
+		_, err := New([]string{"/"}, []string{"file1"}, WithWorkers(-1))
+
+		assert.ErrorIs(t, err, errMessNegativeWorkers)
+	})
+}
+
+func TestWithFailFast(t *testing.T) {
+	cfg, err := New([]string{"/"}, []string{"file1"}, WithFailFast(true))
+
+	assert.NoError(t, err)
+	assert.True(t, cfg.FailFast)
+}
+
+func TestWithOnError(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   ErrorPolicy
+	}{
+		{policy: "", want: ErrorAbort},
+		{policy: "abort", want: ErrorAbort},
+		{policy: "skip", want: ErrorSkip},
+		{policy: " warn ", want: ErrorWarn},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{}
+		err := WithOnError(tt.policy)(cfg)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, cfg.OnError)
+	}
+
+	t.Run("unknown policy", func(t *testing.T) {
+		err := WithOnError("ignore")(&Config{})
+
+		assert.ErrorIs(t, err, errMessUnknownErrorPolicy)
+	})
+}
+
+func TestWithFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		want   Format
+	}{
+		{format: "", want: FormatText},
+		{format: "text", want: FormatText},
+		{format: "json", want: FormatJSON},
+		{format: "ndjson", want: FormatNDJSON},
+		{format: "csv", want: FormatCSV},
+		{format: "tsv", want: FormatTSV},
+		{format: "html", want: FormatHTML},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{}
+		err := WithFormat(tt.format)(cfg)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, cfg.Format)
+	}
+
+	t.Run("unknown format", func(t *testing.T) {
+		err := WithFormat("yaml")(&Config{})
+
+		assert.ErrorIs(t, err, errMessUnknownFormat)
+	})
+}
+
+func TestWithTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	funcs := map[string]any{"humanSize": func(int64) string { return "1 KB" }}
+	sample := struct{ Files []struct{ Path string } }{Files: []struct{ Path string }{{Path: "/a"}}}
+
+	write := func(name, text string) string {
+		path := filepath.Join(tmpDir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(text), 0o644))
+
+		return path
+	}
+
+	t.Run("text", func(t *testing.T) {
+		cfg, err := New([]string{"/"}, []string{"a"}, WithTemplate(write("plan.txt", "<{{.}}> {{humanSize 1024}}"), funcs, sample))
+		assert.NoError(t, err)
+
+		var buf bytes.Buffer
+		assert.NoError(t, cfg.Template.Execute(&buf, "a&b"))
+		assert.Equal(t, "<a&b> 1 KB", buf.String())
+	})
+
+	t.Run("html", func(t *testing.T) {
+		cfg, err := New([]string{"/"}, []string{"a"}, WithTemplate(write("plan.HTML", "<p>{{.}}</p>"), funcs, sample))
+		assert.NoError(t, err)
+
+		var buf bytes.Buffer
+		assert.NoError(t, cfg.Template.Execute(&buf, "a&b"))
+		assert.Equal(t, "<p>a&amp;b</p>", buf.String())
+	})
+
+	t.Run("no template", func(t *testing.T) {
+		cfg, err := New([]string{"/"}, []string{"a"}, WithTemplate(" ", funcs, sample))
+		assert.NoError(t, err)
+		assert.Nil(t, cfg.Template)
+	})
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "missing file", path: filepath.Join(tmpDir, "missing.txt")},
+		{name: "syntax error", path: write("broken.txt", "{{range .Files}}")},
+		{name: "unknown func", path: write("func.txt", "{{ageOf .}}")},
+		{name: "unknown field", path: write("field.txt", "{{.Nope}}")},
+		{name: "unknown field in range", path: write("range.html", "{{range .Files}}{{.Size}}{{end}}")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New([]string{"/"}, []string{"a"}, WithTemplate(tt.path, funcs, sample))
+
+			assert.ErrorIs(t, err, errMessInvalidTemplate)
+		})
+	}
+
+	t.Run("with format", func(t *testing.T) {
+		_, err := New([]string{"/"}, []string{"a"}, WithFormat("json"), WithTemplate(write("plan.txt", "{{.}}"), funcs, sample))
+
+		assert.ErrorIs(t, err, errMessTemplateWithFormat)
+	})
+}
+
+func TestWithSort(t *testing.T) {
+	tests := []struct {
+		key  string
+		want SortKey
+	}{
+		{key: "", want: SortNone},
+		{key: "path", want: SortPath},
+		{key: "size", want: SortSize},
+		{key: "mtime", want: SortMTime},
+		{key: "dir", want: SortDir},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{}
+		err := WithSort(tt.key)(cfg)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, cfg.Sort)
+	}
+
+	t.Run("unknown key", func(t *testing.T) {
+		err := WithSort("name")(&Config{})
+
+		assert.ErrorIs(t, err, errMessUnknownSortKey)
+	})
+
+	t.Run("reverse", func(t *testing.T) {
+		cfg, err := New([]string{"/"}, []string{"a"}, WithSort("size"), WithReverse(true))
+
+		assert.NoError(t, err)
+		assert.Equal(t, SortSize, cfg.Sort)
+		assert.True(t, cfg.Reverse)
+	})
+}
@@ -0,0 +1,130 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Exclusion is a compiled -e entry. An entry without a slash matches the base
+// name of any file or directory. Other entries match the slash-separated path
+// segment by segment, where "**" stands for any number of directories:
+// absolute entries match the full path, relative ones the path inside a root.
+type Exclusion struct {
+	Pattern  string
+	Name     bool
+	Abs      bool
+	Segments []string
+}
+
+func compileExclusion(entry string) (Exclusion, error) {
+	e := Exclusion{Pattern: entry, Abs: filepath.IsAbs(entry)}
+	p := strings.TrimRight(filepath.ToSlash(entry), "/")
+
+	e.Name = !strings.Contains(p, "/") && !e.Abs
+
+	if e.Abs {
+		p = filepath.ToSlash(filepath.Clean(entry))
+	} else {
+		p = path.Clean(p)
+	}
+
+	e.Segments = strings.Split(p, "/")
+
+	for i, s := range e.Segments {
+		s = negateClasses(s)
+		e.Segments[i] = s
+
+		if _, err := filepath.Match(s, ""); err != nil {
+			return Exclusion{}, fmt.Errorf("%w %q: %v", errMessInvalidExclusion, entry, err)
+		}
+	}
+
+	return e, nil
+}
+
+// canMatch reports whether e can match anything inside one of the roots.
+func (e Exclusion) canMatch(roots []string) bool {
+	if !e.Abs {
+		return e.Segments[0] != "." && e.Segments[0] != ".."
+	}
+
+	if hasMeta(e.Segments) {
+		return true
+	}
+
+	return slices.ContainsFunc(roots, func(root string) bool {
+		rel, err := filepath.Rel(root, e.Pattern)
+
+		return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	})
+}
+
+// exists reports whether a literal path exclusion names an existing path.
+func (e Exclusion) exists(roots []string) bool {
+	if e.Name || hasMeta(e.Segments) {
+		return true
+	}
+
+	if e.Abs {
+		_, err := os.Lstat(e.Pattern)
+
+		return !os.IsNotExist(err)
+	}
+
+	return slices.ContainsFunc(roots, func(root string) bool {
+		_, err := os.Lstat(filepath.Join(root, filepath.FromSlash(strings.Join(e.Segments, "/"))))
+
+		return !os.IsNotExist(err)
+	})
+}
+
+func (c *Config) compileExclusions() error {
+	for _, entry := range c.ExcDirs {
+		if entry == "" {
+			continue
+		}
+
+		e, err := compileExclusion(entry)
+		if err != nil {
+			return err
+		}
+
+		if !e.canMatch(c.Dirs) {
+			fmt.Fprintf(c.ErrStream, "Warning: exclusion %q can never match inside %s\n", entry, quoteList(c.Dirs))
+
+			continue
+		}
+
+		if !e.exists(c.Dirs) {
+			fmt.Fprintf(c.ErrStream, "Warning: exclusion %q does not exist inside %s\n", entry, quoteList(c.Dirs))
+		}
+
+		c.Exclusions = append(c.Exclusions, e)
+	}
+
+	return nil
+}
+
+func hasMeta(segments []string) bool {
+	for _, s := range segments {
+		if strings.ContainsAny(s, `*?[\`) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func quoteList(list []string) string {
+	quoted := make([]string, 0, len(list))
+
+	for _, v := range list {
+		quoted = append(quoted, fmt.Sprintf("%q", v))
+	}
+
+	return strings.Join(quoted, ", ")
+}
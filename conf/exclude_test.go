@@ -0,0 +1,110 @@
+package conf
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_compileExclusion(t *testing.T) {
+	tests := []struct {
+		entry string
+		want  Exclusion
+	}{
+		{entry: "node_modules", want: Exclusion{Pattern: "node_modules", Name: true, Segments: []string{"node_modules"}}},
+		{entry: ".snap*", want: Exclusion{Pattern: ".snap*", Name: true, Segments: []string{".snap*"}}},
+		{entry: "[!.]*", want: Exclusion{Pattern: "[!.]*", Name: true, Segments: []string{"[^.]*"}}},
+		{entry: "logs/[!a]*/old", want: Exclusion{Pattern: "logs/[!a]*/old", Segments: []string{"logs", "[^a]*", "old"}}},
+		{entry: "cache/", want: Exclusion{Pattern: "cache/", Name: true, Segments: []string{"cache"}}},
+		{entry: "/var/log/journal", want: Exclusion{Pattern: "/var/log/journal", Abs: true, Segments: []string{"", "var", "log", "journal"}}},
+		{entry: "/var/log/nginx/old/", want: Exclusion{Pattern: "/var/log/nginx/old/", Abs: true, Segments: []string{"", "var", "log", "nginx", "old"}}},
+		{entry: "./journal", want: Exclusion{Pattern: "./journal", Segments: []string{"journal"}}},
+		{entry: "projects/*/cache", want: Exclusion{Pattern: "projects/*/cache", Segments: []string{"projects", "*", "cache"}}},
+		{entry: "**/vendor/**", want: Exclusion{Pattern: "**/vendor/**", Segments: []string{"**", "vendor", "**"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			got, err := compileExclusion(tt.entry)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("invalid glob", func(t *testing.T) {
+		_, err := compileExclusion("projects/[a-/cache")
+
+		assert.ErrorIs(t, err, errMessInvalidExclusion)
+	})
+}
+
+func TestExclusion_canMatch(t *testing.T) {
+	roots := []string{"/var/log", "/srv/app"}
+
+	for _, entry := range []string{"journal", "/var/log/journal", "/srv/app/cache/", "nginx/old", "/srv/*/cache"} {
+		e, err := compileExclusion(entry)
+
+		assert.NoError(t, err)
+		assert.True(t, e.canMatch(roots), entry)
+	}
+
+	for _, entry := range []string{"/var/lib", "/var", "/var/log", "/srv/application", "..", "../other", "a/../../b"} {
+		e, err := compileExclusion(entry)
+
+		assert.NoError(t, err)
+		assert.False(t, e.canMatch(roots), entry)
+	}
+}
+
+func TestNewExclusions(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "journal"), 0o755))
+
+	var errStream bytes.Buffer
+
+	cfg, err := New(
+		[]string{dir},
+		[]string{"file1"},
+		WithErrStream(&errStream),
+		WithExcludeDir(filepath.Join(dir, "journal")+",.snapshots,/elsewhere,missing/dir,**/vendor/**"),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "journal"), ".snapshots", "missing/dir", "**/vendor/**"}, patterns(cfg.Exclusions))
+	assert.Equal(t,
+		"Warning: exclusion \"/elsewhere\" can never match inside \""+dir+"\"\n"+
+			"Warning: exclusion \"missing/dir\" does not exist inside \""+dir+"\"\n",
+		errStream.String())
+
+	t.Run("several roots", func(t *testing.T) {
+		other := t.TempDir()
+		assert.NoError(t, os.Mkdir(filepath.Join(other, "cache"), 0o755))
+
+		var errStream bytes.Buffer
+
+		cfg, err := New(
+			[]string{dir, other},
+			[]string{"file1"},
+			WithErrStream(&errStream),
+			WithExcludeDir("cache,journal/,"+filepath.Join(other, "cache")+",/elsewhere"),
+		)
+
+		assert.NoError(t, err)
+		assert.Len(t, cfg.Exclusions, 3)
+		assert.Equal(t, "Warning: exclusion \"/elsewhere\" can never match inside \""+dir+"\", \""+other+"\"\n", errStream.String())
+	})
+}
+
+func patterns(exclusions []Exclusion) []string {
+	res := make([]string, 0, len(exclusions))
+
+	for _, e := range exclusions {
+		res = append(res, e.Pattern)
+	}
+
+	return res
+}
@@ -0,0 +1,48 @@
+package conf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FileType is a set of file kinds a matched file must belong to. An empty
+// set accepts any kind.
+type FileType uint8
+
+const (
+	TypeRegular FileType = 1 << iota
+	TypeSymlink
+	TypeBrokenLink
+	TypeEmpty
+	TypeFIFO
+	TypeSocket
+)
+
+var fileTypeNames = map[string]FileType{
+	"f":           TypeRegular,
+	"l":           TypeSymlink,
+	"broken-link": TypeBrokenLink,
+	"empty":       TypeEmpty,
+	"p":           TypeFIFO,
+	"s":           TypeSocket,
+}
+
+func parseFileTypes(list string) (FileType, error) {
+	var types FileType
+
+	for _, v := range strings.Split(list, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		t, ok := fileTypeNames[v]
+		if !ok {
+			return 0, fmt.Errorf("%w %q: expected f, l, broken-link, empty, p or s", errMessUnknownFileType, v)
+		}
+
+		types |= t
+	}
+
+	return types, nil
+}
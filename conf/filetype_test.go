@@ -0,0 +1,35 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseFileTypes(t *testing.T) {
+	tests := []struct {
+		got  string
+		want FileType
+	}{
+		{got: "", want: 0},
+		{got: "f", want: TypeRegular},
+		{got: "broken-link", want: TypeBrokenLink},
+		{got: "f, l ,broken-link,empty,p,s", want: TypeRegular | TypeSymlink | TypeBrokenLink | TypeEmpty | TypeFIFO | TypeSocket},
+		{got: "l,l,", want: TypeSymlink},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.got, func(t *testing.T) {
+			got, err := parseFileTypes(tt.got)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("unknown type", func(t *testing.T) {
+		_, err := parseFileTypes("f,d")
+
+		assert.ErrorIs(t, err, errMessUnknownFileType)
+	})
+}
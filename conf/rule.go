@@ -0,0 +1,239 @@
+package conf
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type MatchMode int
+
+const (
+	MatchExact MatchMode = iota
+	MatchGlob
+	MatchRegexp
+)
+
+// Rule is a single file name pattern together with the matcher chosen for it.
+// Exact rules with a non-empty Sep match any part of the name split by Sep.
+// Negated rules exclude files matched by the other rules. Rules with Fold
+// compare names case-insensitively; their Globs are already folded.
+type Rule struct {
+	Mode    MatchMode
+	Pattern string
+	Sep     string
+	Negate  bool
+	Fold    bool
+	Globs   []string
+	Regexp  *regexp.Regexp
+}
+
+func (r Rule) equal(o Rule) bool {
+	return r.Mode == o.Mode && r.Pattern == o.Pattern && r.Sep == o.Sep && r.Negate == o.Negate && r.Fold == o.Fold
+}
+
+// FoldCase maps s to the form used for case-insensitive comparison. Going
+// through upper case first also folds letters like the Greek final sigma.
+func FoldCase(s string) string {
+	return strings.ToLower(strings.ToUpper(s))
+}
+
+// parseRule builds a rule from a command line pattern. A leading "!" negates
+// the rule. The pattern may then start with a prefix choosing its matcher:
+// "exact:", "glob:", "re:" or "sep=X:", where X is the separator. Patterns
+// without a prefix use mode and sep. With fold the rule ignores case.
+func parseRule(pattern string, mode MatchMode, sep string, fold bool) (Rule, error) {
+	body, negate := strings.CutPrefix(pattern, "!")
+	r := Rule{Mode: mode, Pattern: body}
+
+	if mode == MatchExact {
+		r.Sep = sep
+	}
+
+	switch {
+	case strings.HasPrefix(body, "exact:"):
+		r = Rule{Mode: MatchExact, Pattern: strings.TrimPrefix(body, "exact:")}
+	case strings.HasPrefix(body, "glob:"):
+		r = Rule{Mode: MatchGlob, Pattern: strings.TrimPrefix(body, "glob:")}
+	case strings.HasPrefix(body, "re:"):
+		r = Rule{Mode: MatchRegexp, Pattern: strings.TrimPrefix(body, "re:")}
+	case strings.HasPrefix(body, "sep="):
+		rest := strings.TrimPrefix(body, "sep=")
+
+		i := -1
+		if rest != "" {
+			i = strings.Index(rest[1:], ":")
+		}
+
+		if i < 0 {
+			return Rule{}, fmt.Errorf("%w %q: expected sep=<separator>:<name>", errMessInvalidPattern, pattern)
+		}
+
+		r = Rule{Mode: MatchExact, Pattern: rest[i+2:], Sep: rest[:i+1]}
+	}
+
+	if r.Pattern == "" {
+		return Rule{}, fmt.Errorf("%w %q: empty pattern", errMessInvalidPattern, pattern)
+	}
+
+	r.Negate = negate
+	r.Fold = fold
+
+	switch r.Mode {
+	case MatchGlob:
+		glob := r.Pattern
+		if fold {
+			glob = FoldCase(glob)
+		}
+
+		for _, g := range expandBraces(glob) {
+			g = negateClasses(g)
+
+			if _, err := filepath.Match(g, ""); err != nil {
+				return Rule{}, fmt.Errorf("%w %q: %v", errMessInvalidPattern, pattern, err)
+			}
+
+			if !slices.Contains(r.Globs, g) {
+				r.Globs = append(r.Globs, g)
+			}
+		}
+	case MatchRegexp:
+		expr := r.Pattern
+		if fold {
+			expr = "(?i)" + expr
+		}
+
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return Rule{}, fmt.Errorf("%w %q: %v", errMessInvalidPattern, pattern, err)
+		}
+
+		r.Regexp = re
+	}
+
+	return r, nil
+}
+
+// expandBraces expands shell-style alternatives, so "a{b,c{d,e}}" becomes
+// "ab", "acd" and "ace". Braces without a comma and unbalanced braces are
+// kept as is, like in the shell.
+func expandBraces(pattern string) []string {
+	start, end, depth := -1, -1, 0
+
+loop:
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '[':
+			i = skipClass(pattern, i)
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth == 0 {
+				end = i
+				break loop
+			}
+		}
+	}
+
+	if end < 0 {
+		return []string{pattern}
+	}
+
+	prefix, suffix := pattern[:start], pattern[end+1:]
+	alts := splitAlternatives(pattern[start+1 : end])
+	res := make([]string, 0, len(alts))
+
+	if len(alts) == 1 {
+		for _, s := range expandBraces(suffix) {
+			res = append(res, prefix+"{"+alts[0]+"}"+s)
+		}
+
+		return res
+	}
+
+	for _, alt := range alts {
+		res = append(res, expandBraces(prefix+alt+suffix)...)
+	}
+
+	return res
+}
+
+func splitAlternatives(body string) []string {
+	var alts []string
+	last, depth := 0, 0
+
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '[':
+			i = skipClass(body, i)
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				alts = append(alts, body[last:i])
+				last = i + 1
+			}
+		}
+	}
+
+	return append(alts, body[last:])
+}
+
+// negateClasses rewrites the shell negation "[!...]" to "[^...]", the form
+// filepath.Match understands. Without it "!" would be a member of the class.
+func negateClasses(glob string) string {
+	b := []byte(glob)
+
+	for i := 0; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '[':
+			if i+1 < len(b) && b[i+1] == '!' {
+				b[i+1] = '^'
+			}
+
+			i = skipClass(glob, i)
+		}
+	}
+
+	return string(b)
+}
+
+// skipClass returns the index of the "]" closing the character class that
+// starts at i, or i itself if the class is not closed.
+func skipClass(pattern string, i int) int {
+	j := i + 1
+	if j < len(pattern) && (pattern[j] == '^' || pattern[j] == '!') {
+		j++
+	}
+	if j < len(pattern) && pattern[j] == ']' {
+		j++
+	}
+
+	for ; j < len(pattern); j++ {
+		switch pattern[j] {
+		case '\\':
+			j++
+		case ']':
+			return j
+		}
+	}
+
+	return i
+}
@@ -0,0 +1,182 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		mode    MatchMode
+		sep     string
+		want    Rule
+	}{
+		{
+			name:    "default exact",
+			pattern: ".DS_Store",
+			want:    Rule{Mode: MatchExact, Pattern: ".DS_Store"},
+		},
+		{
+			name:    "default separator",
+			pattern: "temp",
+			sep:     "-",
+			want:    Rule{Mode: MatchExact, Pattern: "temp", Sep: "-"},
+		},
+		{
+			name:    "default glob ignores separator",
+			pattern: "cache-*",
+			mode:    MatchGlob,
+			sep:     "-",
+			want:    Rule{Mode: MatchGlob, Pattern: "cache-*", Globs: []string{"cache-*"}},
+		},
+		{
+			name:    "exact prefix drops default separator",
+			pattern: "exact:.DS_Store",
+			mode:    MatchGlob,
+			sep:     "-",
+			want:    Rule{Mode: MatchExact, Pattern: ".DS_Store"},
+		},
+		{
+			name:    "glob prefix",
+			pattern: "glob:cache-{a,b}",
+			want:    Rule{Mode: MatchGlob, Pattern: "cache-{a,b}", Globs: []string{"cache-a", "cache-b"}},
+		},
+		{
+			name:    "negated character class",
+			pattern: "glob:s_[!0-9].{tmp,[!a]og}",
+			want:    Rule{Mode: MatchGlob, Pattern: "s_[!0-9].{tmp,[!a]og}", Globs: []string{"s_[^0-9].tmp", "s_[^0-9].[^a]og"}},
+		},
+		{
+			name:    "literal exclamation mark",
+			pattern: "glob:[a!]-\\[!x]-!",
+			want:    Rule{Mode: MatchGlob, Pattern: "[a!]-\\[!x]-!", Globs: []string{"[a!]-\\[!x]-!"}},
+		},
+		{
+			name:    "separator prefix",
+			pattern: "sep=_:temp",
+			want:    Rule{Mode: MatchExact, Pattern: "temp", Sep: "_"},
+		},
+		{
+			name:    "multi-character separator",
+			pattern: "sep=--:temp",
+			want:    Rule{Mode: MatchExact, Pattern: "temp", Sep: "--"},
+		},
+		{
+			name:    "colon as separator",
+			pattern: "sep=::temp",
+			want:    Rule{Mode: MatchExact, Pattern: "temp", Sep: ":"},
+		},
+		{
+			name:    "negated default glob",
+			pattern: "!*-keep.log",
+			mode:    MatchGlob,
+			want:    Rule{Mode: MatchGlob, Pattern: "*-keep.log", Negate: true, Globs: []string{"*-keep.log"}},
+		},
+		{
+			name:    "negated prefixed pattern",
+			pattern: "!sep=_:audit",
+			want:    Rule{Mode: MatchExact, Pattern: "audit", Sep: "_", Negate: true},
+		},
+		{
+			name:    "exclamation mark inside exact pattern",
+			pattern: "exact:!important",
+			want:    Rule{Mode: MatchExact, Pattern: "!important"},
+		},
+		{
+			name:    "prefix is only checked once",
+			pattern: "exact:glob:name",
+			want:    Rule{Mode: MatchExact, Pattern: "glob:name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRule(tt.pattern, tt.mode, tt.sep, false)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("regexp prefix", func(t *testing.T) {
+		got, err := parseRule(`re:^temp_\d+$`, MatchExact, "-", false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, MatchRegexp, got.Mode)
+		assert.Equal(t, "", got.Sep)
+		assert.True(t, got.Regexp.MatchString("temp_2025"))
+	})
+
+	t.Run("invalid patterns", func(t *testing.T) {
+		for _, p := range []string{"exact:", "re:", "sep=_", "sep=", "sep=_:", "glob:[a-", "re:(a", "!"} {
+			_, err := parseRule(p, MatchExact, "", false)
+
+			assert.ErrorIs(t, err, errMessInvalidPattern, p)
+		}
+	})
+}
+
+func Test_parseRuleFold(t *testing.T) {
+	t.Run("exact keeps the original pattern", func(t *testing.T) {
+		got, err := parseRule("Backup.ZIP", MatchExact, "", true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Rule{Mode: MatchExact, Pattern: "Backup.ZIP", Fold: true}, got)
+	})
+
+	t.Run("glob is folded", func(t *testing.T) {
+		got, err := parseRule("glob:ОТЧЁТ-*.{ZIP,Tar}", MatchExact, "", true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"отчёт-*.zip", "отчёт-*.tar"}, got.Globs)
+	})
+
+	t.Run("negated class is folded", func(t *testing.T) {
+		got, err := parseRule("glob:S_[!A-Z].TMP", MatchExact, "", true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"s_[^a-z].tmp"}, got.Globs)
+	})
+
+	t.Run("regexp ignores case", func(t *testing.T) {
+		got, err := parseRule("re:^straße-\\d+$", MatchExact, "", true)
+
+		assert.NoError(t, err)
+		assert.True(t, got.Regexp.MatchString("STRAßE-1"))
+	})
+}
+
+func TestFoldCase(t *testing.T) {
+	assert.Equal(t, "backup.zip", FoldCase("BACKUP.zip"))
+	assert.Equal(t, "ärger", FoldCase("ÄRGER"))
+	assert.Equal(t, FoldCase("ΟΔΟΣ"), FoldCase("οδος"))
+	assert.Equal(t, FoldCase("ΟΔΟΣ"), FoldCase("οδοσ"))
+}
+
+func Test_expandBraces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{pattern: "file.log", want: []string{"file.log"}},
+		{pattern: "*.{log,tmp}", want: []string{"*.log", "*.tmp"}},
+		{pattern: "a{b,c{d,e}}f", want: []string{"abf", "acdf", "acef"}},
+		{pattern: "{a,b}-{1,2}", want: []string{"a-1", "a-2", "b-1", "b-2"}},
+		{pattern: "{a}-{1,2}", want: []string{"{a}-1", "{a}-2"}},
+		{pattern: "x{a,", want: []string{"x{a,"}},
+		{pattern: `\{a,b}`, want: []string{`\{a,b}`}},
+		{pattern: "[{]{a,b}", want: []string{"[{]a", "[{]b"}},
+		{pattern: "{,old-}log", want: []string{"log", "old-log"}},
+		{pattern: "[!,}]{a,b}", want: []string{"[!,}]a", "[!,}]b"}},
+		{pattern: "{x[!,]y,z}", want: []string{"x[!,]y", "z"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			assert.Equal(t, tt.want, expandBraces(tt.pattern))
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package conf
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var sizeUnits = map[string]float64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+	"P": 1 << 50,
+	"E": 1 << 60,
+}
+
+// parseSize reads a size like "512", "10K", "1.5M" or "2 GB". Units are powers
+// of 1024, the same as in the report, and the "B" and "iB" suffixes are optional.
+func parseSize(v string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(v))
+	s = strings.TrimSuffix(s, "B")
+	s = strings.TrimSuffix(s, "I")
+
+	i := len(s)
+	for i > 0 && (s[i-1] < '0' || s[i-1] > '9') && s[i-1] != '.' {
+		i--
+	}
+
+	unit, ok := sizeUnits[strings.TrimLeft(s[i:], " ")]
+	if !ok || i == 0 {
+		return 0, fmt.Errorf("%w %q: expected a size like 512, 10K, 1.5M or 2G", errMessInvalidSize, v)
+	}
+
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w %q: expected a size like 512, 10K, 1.5M or 2G", errMessInvalidSize, v)
+	}
+
+	size := math.Round(n * unit)
+	if size >= math.MaxInt64 {
+		return 0, fmt.Errorf("%w %q: size is too large", errMessInvalidSize, v)
+	}
+
+	return int64(size), nil
+}
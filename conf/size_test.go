@@ -0,0 +1,44 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseSize(t *testing.T) {
+	tests := []struct {
+		got  string
+		want int64
+	}{
+		{got: "0", want: 0},
+		{got: "512", want: 512},
+		{got: "512B", want: 512},
+		{got: "10K", want: 10 * 1024},
+		{got: "10k", want: 10 * 1024},
+		{got: "10KB", want: 10 * 1024},
+		{got: "10KiB", want: 10 * 1024},
+		{got: "1.5M", want: 1536 * 1024},
+		{got: "2G", want: 2 << 30},
+		{got: " 2 GB ", want: 2 << 30},
+		{got: "1T", want: 1 << 40},
+		{got: "0.5K", want: 512},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.got, func(t *testing.T) {
+			got, err := parseSize(tt.got)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("invalid sizes", func(t *testing.T) {
+		for _, v := range []string{"", "K", "-1K", "10X", "1..5M", "16E", "10 K B"} {
+			_, err := parseSize(v)
+
+			assert.ErrorIs(t, err, errMessInvalidSize, v)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package conf
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Template is a user report template, parsed with html/template for .html
+// and .htm files and with text/template otherwise.
+type Template interface {
+	Execute(w io.Writer, data any) error
+}
+
+// WithTemplate reads and parses the report template at path. funcs are the
+// functions the report provides to templates, parsing fails on any other.
+// The template is also executed against sample, data shaped like the real
+// report, so a wrong field fails here and not after the files are removed.
+func WithTemplate(path string, funcs map[string]any, sample any) Option {
+	return func(c *Config) error {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return nil
+		}
+
+		tmpl, err := parseTemplate(path, funcs)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errMessInvalidTemplate, err)
+		}
+
+		if err := tmpl.Execute(io.Discard, sample); err != nil {
+			return fmt.Errorf("%w: %w", errMessInvalidTemplate, err)
+		}
+
+		c.Template = tmpl
+
+		return nil
+	}
+}
+
+func parseTemplate(path string, funcs map[string]any) (Template, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := filepath.Base(path)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		return htmltemplate.New(name).Funcs(funcs).Parse(string(text))
+	default:
+		return template.New(name).Funcs(funcs).Parse(string(text))
+	}
+}
@@ -0,0 +1,158 @@
+package remover
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"time"
+
+	"github.com/figurecode/files-remover/scanner"
+)
+
+// EventVersion is the version of the NDJSON event schema, written to every
+// event as "v". It goes up when a field is removed or changes its meaning;
+// new fields and events keep the version.
+const EventVersion = 1
+
+type eventHeader struct {
+	Version int       `json:"v"`
+	Event   string    `json:"event"`
+	Time    time.Time `json:"time"`
+}
+
+type startedEvent struct {
+	eventHeader
+	DryRun bool     `json:"dry_run"`
+	Roots  []string `json:"roots"`
+}
+
+type fileEvent struct {
+	eventHeader
+	ReportFile
+}
+
+type skippedEvent struct {
+	eventHeader
+	ReportSkipped
+}
+
+type failedEvent struct {
+	eventHeader
+	ReportFailure
+}
+
+type summaryEvent struct {
+	eventHeader
+	DryRun      bool  `json:"dry_run"`
+	Count       int   `json:"count"`
+	TotalBytes  int64 `json:"total_bytes"`
+	Skipped     int   `json:"skipped"`
+	Failed      int   `json:"failed"`
+	Interrupted bool  `json:"interrupted"`
+}
+
+// EventWriter writes one JSON event per line as the run goes: scan_started,
+// then match and skipped while the scan finds files, removed, skipped and
+// failed while they are removed, and summary at the end.
+type EventWriter struct {
+	enc     *json.Encoder
+	now     func() time.Time
+	err     error
+	summary summaryEvent
+}
+
+func NewEventWriter(out io.Writer, dryRun bool) *EventWriter {
+	return &EventWriter{
+		enc:     json.NewEncoder(out),
+		now:     time.Now,
+		summary: summaryEvent{DryRun: dryRun},
+	}
+}
+
+// Start writes the scan_started event.
+func (e *EventWriter) Start(roots []string) {
+	e.write(&startedEvent{eventHeader: e.header("scan_started"), DryRun: e.summary.DryRun, Roots: roots})
+}
+
+// Stream passes the scan through and writes a match event for every file to
+// be deleted and a skipped event for protected and unreadable paths.
+func (e *EventWriter) Stream(files iter.Seq2[scanner.FileInfo, error]) iter.Seq2[scanner.FileInfo, error] {
+	return func(yield func(scanner.FileInfo, error) bool) {
+		for f, err := range files {
+			switch {
+			case err != nil:
+			case f.Err != nil:
+				e.skipped(f.Path, f.Err.Error())
+			case f.Protected:
+				e.skipped(f.Path, "protected")
+			default:
+				e.write(&fileEvent{eventHeader: e.header("match"), ReportFile: reportFile(f)})
+
+				if e.summary.DryRun {
+					e.summary.Count++
+					e.summary.TotalBytes += f.Size
+				}
+			}
+
+			if !yield(f, err) {
+				return
+			}
+		}
+	}
+}
+
+// Collect writes the events of a dry run, reading the whole scan.
+func (e *EventWriter) Collect(files iter.Seq2[scanner.FileInfo, error]) error {
+	for _, err := range e.Stream(files) {
+		if isInterrupt(err) {
+			return err
+		}
+
+		if err != nil {
+			return fmt.Errorf("traversing directories: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Record writes the removed, skipped and failed events of a real deletion.
+// It matches Options.OnFile.
+func (e *EventWriter) Record(f scanner.FileInfo, outcome Outcome, err error) {
+	switch outcome {
+	case Removed:
+		e.write(&fileEvent{eventHeader: e.header("removed"), ReportFile: reportFile(f)})
+		e.summary.Count++
+		e.summary.TotalBytes += f.Size
+	case Gone:
+		e.skipped(f.Path, "already gone")
+	case Failed:
+		e.summary.Failed++
+		e.write(&failedEvent{eventHeader: e.header("failed"), ReportFailure: reportFailure(f, err)})
+	}
+}
+
+// Finish writes the summary event and returns the first write error.
+func (e *EventWriter) Finish(interrupted bool) error {
+	e.summary.Interrupted = interrupted
+	e.summary.eventHeader = e.header("summary")
+	e.write(&e.summary)
+
+	return e.err
+}
+
+func (e *EventWriter) skipped(path, reason string) {
+	e.summary.Skipped++
+	e.write(&skippedEvent{eventHeader: e.header("skipped"), ReportSkipped: ReportSkipped{Path: path, Reason: reason}})
+}
+
+func (e *EventWriter) header(event string) eventHeader {
+	return eventHeader{Version: EventVersion, Event: event, Time: e.now().UTC()}
+}
+
+func (e *EventWriter) write(event any) {
+	if e.err == nil {
+		e.err = e.enc.Encode(event)
+	}
+}
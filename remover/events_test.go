@@ -0,0 +1,168 @@
+package remover
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/figurecode/files-remover/scanner"
+)
+
+func readEvents(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var events []map[string]any
+
+	lines := bufio.NewScanner(buf)
+	for lines.Scan() {
+		var event map[string]any
+		if err := json.Unmarshal(lines.Bytes(), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", lines.Text(), err)
+		}
+
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func eventNames(events []map[string]any) []string {
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, event["event"].(string))
+	}
+
+	return names
+}
+
+func TestEventWriterDryRun(t *testing.T) {
+	mtime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := time.Date(2025, 3, 2, 8, 0, 0, 0, time.UTC)
+
+	files := stream(
+		scanner.FileInfo{Path: "/srv/a/1.tmp", Root: "/srv/a", Pattern: "*.tmp", Size: 1024, ModTime: mtime},
+		scanner.FileInfo{Path: "/srv/a/keep.tmp", Root: "/srv/a", Pattern: "*.tmp", Size: 10, ModTime: mtime, Protected: true},
+		scanner.FileInfo{Path: "/srv/a/locked", Root: "/srv/a", Err: syscall.EACCES},
+	)
+
+	var buf bytes.Buffer
+
+	events := NewEventWriter(&buf, true)
+	events.now = func() time.Time { return now }
+
+	events.Start([]string{"/srv/a"})
+
+	if err := events.Collect(files); err != nil {
+		t.Fatalf("Collect() error %v", err)
+	}
+
+	if err := events.Finish(false); err != nil {
+		t.Fatalf("Finish() error %v", err)
+	}
+
+	got := readEvents(t, &buf)
+	want := []map[string]any{
+		{"v": 1.0, "event": "scan_started", "time": "2025-03-02T08:00:00Z", "dry_run": true, "roots": []any{"/srv/a"}},
+		{"v": 1.0, "event": "match", "time": "2025-03-02T08:00:00Z", "path": "/srv/a/1.tmp", "size": 1024.0, "mtime": "2025-03-01T12:00:00Z", "pattern": "*.tmp", "root": "/srv/a"},
+		{"v": 1.0, "event": "skipped", "time": "2025-03-02T08:00:00Z", "path": "/srv/a/keep.tmp", "reason": "protected"},
+		{"v": 1.0, "event": "skipped", "time": "2025-03-02T08:00:00Z", "path": "/srv/a/locked", "reason": syscall.EACCES.Error()},
+		{"v": 1.0, "event": "summary", "time": "2025-03-02T08:00:00Z", "dry_run": true, "count": 1.0, "total_bytes": 1024.0, "skipped": 2.0, "failed": 0.0, "interrupted": false},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong events\ngot:  %v\nwant: %v", got, want)
+	}
+
+	t.Run("Interrupted", func(t *testing.T) {
+		files := func(yield func(scanner.FileInfo, error) bool) {
+			if yield(scanner.FileInfo{Path: "/tmp/a.log", Size: 1}, nil) {
+				yield(scanner.FileInfo{}, context.Canceled)
+			}
+		}
+
+		var buf bytes.Buffer
+
+		events := NewEventWriter(&buf, true)
+		if err := events.Collect(files); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Collect() error = %v, want context.Canceled", err)
+		}
+
+		if err := events.Finish(true); err != nil {
+			t.Fatalf("Finish() error %v", err)
+		}
+
+		got := readEvents(t, &buf)
+		if names := eventNames(got); !reflect.DeepEqual(names, []string{"match", "summary"}) {
+			t.Fatalf("wrong events %v", names)
+		}
+
+		if got[1]["interrupted"] != true || got[1]["count"] != 1.0 {
+			t.Errorf("wrong summary %v", got[1])
+		}
+	})
+}
+
+func TestEventWriterExecute(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	removed := filepath.Join(tmpDir, "a.log")
+	busy := filepath.Join(tmpDir, "busy")
+
+	if err := os.WriteFile(removed, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(busy, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := stream(
+		scanner.FileInfo{Path: removed, Size: 1},
+		scanner.FileInfo{Path: busy, Size: 1},
+		scanner.FileInfo{Path: filepath.Join(tmpDir, "missing.log"), Size: 1},
+	)
+
+	var buf bytes.Buffer
+
+	events := NewEventWriter(&buf, false)
+
+	_, err := ExecuteStream(t.Context(), events.Stream(files), Options{OnFile: events.Record})
+
+	var removeErr *RemoveError
+	if !errors.As(err, &removeErr) {
+		t.Fatalf("ExecuteStream() error = %v, want *RemoveError", err)
+	}
+
+	if err := events.Finish(false); err != nil {
+		t.Fatalf("Finish() error %v", err)
+	}
+
+	got := readEvents(t, &buf)
+
+	wantNames := []string{"match", "removed", "match", "failed", "match", "skipped", "summary"}
+	if names := eventNames(got); !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("wrong events %v, want %v", names, wantNames)
+	}
+
+	if got[3]["path"] != busy || got[3]["op"] != "remove" || got[3]["errno"] == 0.0 {
+		t.Errorf("wrong failed event %v", got[3])
+	}
+
+	if got[5]["reason"] != "already gone" {
+		t.Errorf("wrong skipped event %v", got[5])
+	}
+
+	summary := got[6]
+	if summary["dry_run"] != false || summary["count"] != 1.0 || summary["total_bytes"] != 1.0 ||
+		summary["skipped"] != 1.0 || summary["failed"] != 1.0 {
+		t.Errorf("wrong summary %v", summary)
+	}
+}
@@ -0,0 +1,65 @@
+package remover
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall"
+)
+
+// Failure is a file the remover could not handle. Errno is zero when the
+// system gave no error number.
+type Failure struct {
+	Path  string
+	Op    string
+	Errno syscall.Errno
+	Err   error
+}
+
+func newFailure(path string, err error) Failure {
+	f := Failure{Path: path, Op: "remove", Err: err}
+
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		f.Path, f.Op, f.Err = pathErr.Path, pathErr.Op, pathErr.Err
+	}
+
+	errors.As(err, &f.Errno)
+
+	return f
+}
+
+func (f Failure) Error() string {
+	if f.Errno != 0 {
+		return fmt.Sprintf("%s %s: %v (errno %d)", f.Op, f.Path, f.Err, f.Errno)
+	}
+
+	return fmt.Sprintf("%s %s: %v", f.Op, f.Path, f.Err)
+}
+
+func (f Failure) Unwrap() error {
+	return f.Err
+}
+
+// RemoveError holds every failure of a removal.
+type RemoveError struct {
+	Failures []Failure
+}
+
+func (e *RemoveError) Error() string {
+	if len(e.Failures) == 1 {
+		return e.Failures[0].Error()
+	}
+
+	return fmt.Sprintf("%d files could not be removed", len(e.Failures))
+}
+
+func (e *RemoveError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+
+	for i, f := range e.Failures {
+		errs[i] = f
+	}
+
+	return errs
+}
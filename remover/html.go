@@ -0,0 +1,185 @@
+package remover
+
+import (
+	"cmp"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const htmlReportTempl = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>files-remover: {{if .DryRun}}cleanup plan{{else}}cleanup result{{end}}</title>
+<style>
+body { font: 14px/1.4 sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { padding: 4px 10px; border-bottom: 1px solid #ddd; text-align: left; }
+th { background: #f4f4f4; }
+th.sortable { cursor: pointer; }
+td.num, th.num { text-align: right; }
+.warn { color: #a00; }
+</style>
+</head>
+<body>
+<h1>{{if .DryRun}}Cleanup plan{{else}}Cleanup result{{end}}</h1>
+<p>Generated {{.Generated.Format "2006-01-02 15:04:05 MST"}}.
+{{if .DryRun}}{{.Count}} files will be deleted, {{humanSize .TotalBytes}} of disk space will be freed.
+{{- else}}{{.Count}} files removed, {{humanSize .TotalBytes}} of disk space freed.{{end}}</p>
+{{- if .Interrupted}}
+<p class="warn">The run was interrupted, the report is incomplete.</p>
+{{- end}}
+
+<h2>Files</h2>
+<table id="files">
+<thead><tr><th class="sortable">Path</th><th class="sortable num">Size</th><th class="sortable num">Age</th><th class="sortable">Modified</th><th class="sortable">Owner</th><th class="sortable">Pattern</th></tr></thead>
+<tbody>
+{{- range .Files}}
+<tr><td>{{.Path}}</td><td class="num" data-sort="{{.Size}}">{{humanSize .Size}}</td><td class="num" data-sort="{{age .ModTime}}">{{humanAge .ModTime}}</td><td data-sort="{{.ModTime.Unix}}">{{.ModTime.Format "2006-01-02 15:04"}}</td><td>{{.Owner}}</td><td>{{.Pattern}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+
+<h2>Totals by directory</h2>
+<table id="dirs">
+<thead><tr><th class="sortable">Directory</th><th class="sortable num">Files</th><th class="sortable num">Size</th></tr></thead>
+<tbody>
+{{- range .Dirs}}
+<tr><td>{{.Dir}}</td><td class="num" data-sort="{{.Files}}">{{.Files}}</td><td class="num" data-sort="{{.Size}}">{{humanSize .Size}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- with .Protected}}
+
+<h2>Protected, kept</h2>
+<table>
+<thead><tr><th>Path</th><th class="num">Size</th><th>Pattern</th></tr></thead>
+<tbody>
+{{- range .}}
+<tr><td>{{.Path}}</td><td class="num">{{humanSize .Size}}</td><td>{{.Pattern}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{- with .Skipped}}
+
+<h2>Skipped</h2>
+<table>
+<thead><tr><th>Path</th><th>Reason</th></tr></thead>
+<tbody>
+{{- range .}}
+<tr><td>{{.Path}}</td><td>{{.Reason}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{- with .Failed}}
+
+<h2 class="warn">Failed</h2>
+<table>
+<thead><tr><th>Path</th><th>Operation</th><th>Error</th></tr></thead>
+<tbody>
+{{- range .}}
+<tr><td>{{.Path}}</td><td>{{.Op}}</td><td>{{.Error}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+
+<script>
+document.querySelectorAll("th.sortable").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var tbody = th.closest("table").tBodies[0];
+    var col = th.cellIndex;
+    var asc = th.dataset.order !== "asc";
+    var key = function (row) {
+      var cell = row.cells[col];
+      return cell.dataset.sort !== undefined ? Number(cell.dataset.sort) : cell.textContent;
+    };
+    var rows = Array.from(tbody.rows).sort(function (a, b) {
+      var x = key(a), y = key(b);
+      var c = typeof x === "number" ? x - y : x.localeCompare(y);
+      return asc ? c : -c;
+    });
+    th.dataset.order = asc ? "asc" : "desc";
+    rows.forEach(function (row) { tbody.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`
+
+// htmlReport is the data of the HTML page: the report, the time it was made
+// and the totals of every directory holding the files.
+type htmlReport struct {
+	*Report
+	Generated time.Time
+	Dirs      []dirTotal
+}
+
+type dirTotal struct {
+	Dir   string
+	Files int
+	Size  int64
+}
+
+// WriteHTML writes the report as a single HTML page. File ages are counted
+// from now.
+func (r *Report) WriteHTML(out io.Writer, now time.Time) error {
+	tmpl, err := template.New("report").
+		Funcs(template.FuncMap(TemplateFuncs)).
+		Funcs(template.FuncMap{
+			"age":      func(t time.Time) int64 { return int64(now.Sub(t).Seconds()) },
+			"humanAge": func(t time.Time) string { return humanAge(now.Sub(t)) },
+		}).
+		Parse(htmlReportTempl)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(out, htmlReport{Report: r, Generated: now, Dirs: dirTotals(r.Files)})
+}
+
+// dirTotals sums the files by their parent directory, in path order.
+func dirTotals(files []ReportFile) []dirTotal {
+	index := make(map[string]int)
+
+	var totals []dirTotal
+
+	for _, f := range files {
+		dir := filepath.Dir(f.Path)
+
+		i, ok := index[dir]
+		if !ok {
+			i = len(totals)
+			index[dir] = i
+			totals = append(totals, dirTotal{Dir: dir})
+		}
+
+		totals[i].Files++
+		totals[i].Size += f.Size
+	}
+
+	slices.SortFunc(totals, func(a, b dirTotal) int { return cmp.Compare(a.Dir, b.Dir) })
+
+	return totals
+}
+
+// humanAge formats a file age with its largest unit, like 3d or 5h.
+func humanAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return "<1m"
+	}
+}
@@ -1,22 +1,84 @@
 package remover
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"os"
 	"text/template"
-)
 
-const debugReportTempl = `{{.FilesCount}} files will be deleted in total
-{{humanSize .Size}} of disk space will be freed
+	"github.com/figurecode/files-remover/scanner"
+)
 
-Files to be deleted:
-{{range .Files}}---------------------------------
+// debugStreamTempl closes the report of DebugStream, after the files have been
+// listed as they were found.
+const debugStreamTempl = `{{if .Protected}}
+Files kept by protect rules:
+{{range .Protected}}---------------------------------
 PATH: {{.}}
-{{end}}
-END
+{{end}}{{end}}{{if .Skipped}}
+Warnings, paths skipped because they could not be read:
+{{range .Skipped}}---------------------------------
+SKIPPED: {{.Path}}: {{.Err}}
+{{end}}{{end}}{{if gt (len .Roots) 1}}
+Totals by directory:
+{{range .Roots}}{{.Root}}: {{.Files}} files, {{humanSize .Size}}
+{{end}}{{end}}
+{{.FilesCount}} files will be deleted in total
+{{humanSize .Size}} of disk space will be freed
+{{if .Interrupted}}INTERRUPTED: the scan was stopped, the list is incomplete
+{{end}}END
 `
 
+// summaryTempl reports what a removal did when it failed for some files or
+// did not run to the end.
+const summaryTempl = `Removed: {{.Removed}} files, {{humanSize .RemovedSize}}
+Skipped: {{.Skipped}} files kept by protect rules or already gone
+{{if .Failures}}Failed: {{len .Failures}} files
+{{range .Failures}}  {{.}}
+{{end}}{{end}}{{if .Interrupted}}{{if .Last}}Remaining: files after {{.Last}} were not checked and are left in place
+{{else}}Remaining: no files were checked, everything is left in place
+{{end}}{{end}}`
+
+// Summary tells what ExecuteStream did. Last is the last file it handled, so
+// after an interrupt everything found after it is left in place.
+type Summary struct {
+	Removed     int
+	RemovedSize int64
+	Skipped     int
+	Failures    []Failure
+	Last        string
+	Interrupted bool
+}
+
+// Outcome is what happened to a file handed to ExecuteStream.
+type Outcome int
+
+const (
+	Removed Outcome = iota
+	// Protected files are kept by a protect rule.
+	Protected
+	// Gone files had disappeared before they could be removed.
+	Gone
+	// Unreadable paths were skipped by the scan.
+	Unreadable
+	Failed
+)
+
+// Options tune ExecuteStream. FailFast stops at the first file that cannot be
+// removed. OnFile, when set, is told about every file with its outcome and
+// the error behind it, if any.
+type Options struct {
+	FailFast bool
+	OnFile   func(f scanner.FileInfo, outcome Outcome, err error)
+}
+
+// TemplateFuncs are the functions available to the report templates,
+// including the ones given with --template.
+var TemplateFuncs = template.FuncMap{"humanSize": humanSize}
+
 func humanSize(bytes int64) string {
 	const unit = 1024
 	if bytes < unit {
@@ -30,48 +92,181 @@ func humanSize(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
-func DebugRemover(files map[string]int64, out io.Writer) error {
-	if len(files) == 0 {
-		files = make(map[string]int64)
-	}
-
+// DebugStream writes the demo report while the scan is still running: every
+// file is listed as soon as it is found, and the totals follow at the end.
+func DebugStream(files iter.Seq2[scanner.FileInfo, error], out io.Writer) error {
 	var reportParam struct {
-		FilesCount int
-		Files      []string
-		Size       int64
+		FilesCount  int
+		Protected   []string
+		Skipped     []scanner.SkippedPath
+		Roots       []scanner.RootTotal
+		Size        int64
+		Interrupted bool
 	}
 	var report = template.Must(
-		template.New("Debug mode").
-			Funcs(template.FuncMap{"humanSize": humanSize}).
-			Parse(debugReportTempl))
+		template.New("Debug stream").
+			Funcs(TemplateFuncs).
+			Parse(debugStreamTempl))
+
+	roots := make(map[string]int)
+	started := false
+
+	// The header is written with the first found file, so a scan that fails
+	// right away prints only the error.
+	begin := func() error {
+		if started {
+			return nil
+		}
+
+		started = true
+		_, err := fmt.Fprint(out, "Files to be deleted:\n")
+
+		return err
+	}
+
+	var scanErr error
+
+	for f, err := range files {
+		if isInterrupt(err) {
+			scanErr = err
+			reportParam.Interrupted = true
+
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("traversing directories: %w", err)
+		}
+
+		if err := begin(); err != nil {
+			return err
+		}
+
+		if f.Err != nil {
+			reportParam.Skipped = append(reportParam.Skipped, scanner.SkippedPath{Path: f.Path, Err: f.Err})
+
+			continue
+		}
+
+		if f.Protected {
+			reportParam.Protected = append(reportParam.Protected, f.Path)
 
-	reportParam.FilesCount = len(files)
-	reportParam.Files = make([]string, 0, len(files))
+			continue
+		}
 
-	for path, size := range files {
-		reportParam.Files = append(reportParam.Files, path)
-		reportParam.Size += size
+		if _, err := fmt.Fprintf(out, "---------------------------------\nPATH: %s\n", f.Path); err != nil {
+			return err
+		}
+
+		i, ok := roots[f.Root]
+		if !ok {
+			i = len(reportParam.Roots)
+			roots[f.Root] = i
+			reportParam.Roots = append(reportParam.Roots, scanner.RootTotal{Root: f.Root})
+		}
+
+		reportParam.Roots[i].Files++
+		reportParam.Roots[i].Size += f.Size
+		reportParam.FilesCount++
+		reportParam.Size += f.Size
+	}
+
+	if err := begin(); err != nil {
+		return err
 	}
 
 	if err := report.Execute(out, reportParam); err != nil {
 		return err
 	}
 
-	return nil
+	return scanErr
 }
 
-func Execute(files map[string]int64) error {
-	if len(files) == 0 {
-		return nil
+// ExecuteStream removes files while the scan is still running. Files kept by
+// protect rules are left in place. A file that cannot be removed is recorded
+// and the removal goes on, unless opts.FailFast is set; the failures are
+// returned in a *RemoveError. When ctx is cancelled it stops before the next
+// file and returns the summary of what was done so far.
+func ExecuteStream(ctx context.Context, files iter.Seq2[scanner.FileInfo, error], opts Options) (Summary, error) {
+	var sum Summary
+
+	notify := func(f scanner.FileInfo, outcome Outcome, err error) {
+		if opts.OnFile != nil {
+			opts.OnFile(f, outcome, err)
+		}
 	}
 
-	for path := range files {
-		err := os.Remove(path)
+	for f, err := range files {
+		if isInterrupt(err) {
+			sum.Interrupted = true
 
-		if !os.IsNotExist(err) && err != nil {
-			return err
+			return sum, err
+		}
+
+		if err != nil {
+			return sum, fmt.Errorf("traversing directories: %w", err)
 		}
+
+		if err := ctx.Err(); err != nil {
+			sum.Interrupted = true
+
+			return sum, err
+		}
+
+		if f.Err != nil {
+			notify(f, Unreadable, f.Err)
+
+			continue
+		}
+
+		sum.Last = f.Path
+
+		if f.Protected {
+			sum.Skipped++
+			notify(f, Protected, nil)
+
+			continue
+		}
+
+		err := os.Remove(f.Path)
+
+		switch {
+		case os.IsNotExist(err):
+			sum.Skipped++
+			notify(f, Gone, err)
+		case err != nil:
+			failure := newFailure(f.Path, err)
+			sum.Failures = append(sum.Failures, failure)
+			notify(f, Failed, failure)
+
+			if opts.FailFast {
+				return sum, &RemoveError{Failures: sum.Failures}
+			}
+		default:
+			sum.Removed++
+			sum.RemovedSize += f.Size
+			notify(f, Removed, nil)
+		}
+	}
+
+	if len(sum.Failures) > 0 {
+		return sum, &RemoveError{Failures: sum.Failures}
 	}
 
-	return nil
+	return sum, nil
+}
+
+// WriteSummary writes the report of a removal that failed for some files or
+// was interrupted.
+func WriteSummary(sum Summary, out io.Writer) error {
+	report := template.Must(
+		template.New("Summary").
+			Funcs(TemplateFuncs).
+			Parse(summaryTempl))
+
+	return report.Execute(out, sum)
+}
+
+func isInterrupt(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
@@ -2,127 +2,391 @@ package remover
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"syscall"
 	"testing"
+
+	"github.com/figurecode/files-remover/conf"
+	"github.com/figurecode/files-remover/scanner"
 )
 
-func TestExecute(t *testing.T) {
-	t.Run("Remove real files", func(t *testing.T) {
-		tmpDir := t.TempDir()
+func TestDebugStream(t *testing.T) {
+	t.Run("Output files as found", func(t *testing.T) {
+		files := stream(
+			scanner.FileInfo{Path: "/var/log/app.log", Root: "/var/log", Size: 1024},
+			scanner.FileInfo{Path: "/var/log/audit.log", Root: "/var/log", Size: 4096, Protected: true},
+			scanner.FileInfo{Path: "/srv/a/tmp/1.tmp", Root: "/srv/a/tmp", Size: 2048},
+		)
+
+		var buf bytes.Buffer
+		if err := DebugStream(files, &buf); err != nil {
+			t.Fatalf("DebugStream() error %v", err)
+		}
+
+		want := "Files to be deleted:\n" +
+			"---------------------------------\nPATH: /var/log/app.log\n" +
+			"---------------------------------\nPATH: /srv/a/tmp/1.tmp\n\n" +
+			"Files kept by protect rules:\n" +
+			"---------------------------------\nPATH: /var/log/audit.log\n\n" +
+			"Totals by directory:\n" +
+			"/var/log: 1 files, 1.0 KB\n" +
+			"/srv/a/tmp: 1 files, 2.0 KB\n\n" +
+			"2 files will be deleted in total\n" +
+			"3.0 KB of disk space will be freed\n" +
+			"END\n"
 
-		files := map[string]int64{
-			filepath.Join(tmpDir, "info-1.log"): 100,
-			filepath.Join(tmpDir, "info-2.log"): 200,
+		if got := buf.String(); got != want {
+			t.Errorf("wrong output\ngot:  %q\nwant: %q", got, want)
 		}
+	})
+
+	t.Run("Does not remove files", func(t *testing.T) {
+		tmpDir := t.TempDir()
 
-		for path := range files {
+		paths := []string{filepath.Join(tmpDir, "info-1.log"), filepath.Join(tmpDir, "info-2.log")}
+
+		for _, path := range paths {
 			if err := os.WriteFile(path, []byte("important"), 0644); err != nil {
 				t.Fatal(err)
 			}
 		}
 
-		if err := Execute(files); err != nil {
-			t.Fatalf("Execute() return error: %v", err)
+		var buf bytes.Buffer
+		if err := DebugStream(stream(scanner.FileInfo{Path: paths[0]}, scanner.FileInfo{Path: paths[1]}), &buf); err != nil {
+			t.Fatal(err)
 		}
 
-		for path := range files {
-			if _, err := os.Stat(path); !os.IsNotExist(err) {
-				t.Errorf("File %q was not deleted", path)
+		for _, path := range paths {
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				t.Fatalf("DebugStream() deleted file %s", path)
 			}
 		}
 	})
 
-	t.Run("File already missing", func(t *testing.T) {
-		files := map[string]int64{
-			"/tmp/file/does-not/exist/really.log": 12345,
+	t.Run("Sorted files in path order", func(t *testing.T) {
+		files := stream(
+			scanner.FileInfo{Path: "/tmp/c.log"},
+			scanner.FileInfo{Path: "/tmp/z.log", Protected: true},
+			scanner.FileInfo{Path: "/tmp/b/a.log"},
+			scanner.FileInfo{Path: "/tmp/a.log"},
+			scanner.FileInfo{Path: "/tmp/y.log", Protected: true},
+			scanner.FileInfo{Path: "/tmp/b.log"},
+		)
+
+		var buf bytes.Buffer
+		if err := DebugStream(Sorted(files, conf.SortNone, false), &buf); err != nil {
+			t.Fatalf("DebugStream() error %v", err)
+		}
+
+		want := "Files to be deleted:\n" +
+			"---------------------------------\nPATH: /tmp/a.log\n" +
+			"---------------------------------\nPATH: /tmp/b.log\n" +
+			"---------------------------------\nPATH: /tmp/b/a.log\n" +
+			"---------------------------------\nPATH: /tmp/c.log\n\n" +
+			"Files kept by protect rules:\n" +
+			"---------------------------------\nPATH: /tmp/y.log\n" +
+			"---------------------------------\nPATH: /tmp/z.log\n"
+
+		if got := buf.String(); !strings.HasPrefix(got, want) {
+			t.Errorf("wrong output\ngot:  %q\nwant prefix: %q", got, want)
+		}
+	})
+
+	t.Run("Output skipped paths", func(t *testing.T) {
+		files := stream(
+			scanner.FileInfo{Path: "/srv/a/locked", Root: "/srv/a", Err: syscall.EACCES},
+			scanner.FileInfo{Path: "/srv/a/1.tmp", Root: "/srv/a", Size: 1024},
+		)
+
+		var buf bytes.Buffer
+		if err := DebugStream(files, &buf); err != nil {
+			t.Fatalf("DebugStream() error %v", err)
+		}
+
+		want := "Files to be deleted:\n" +
+			"---------------------------------\nPATH: /srv/a/1.tmp\n\n" +
+			"Warnings, paths skipped because they could not be read:\n" +
+			"---------------------------------\nSKIPPED: /srv/a/locked: " + syscall.EACCES.Error() + "\n\n" +
+			"1 files will be deleted in total\n"
+
+		if got := buf.String(); !strings.HasPrefix(got, want) {
+			t.Errorf("wrong output\ngot:  %q\nwant prefix: %q", got, want)
+		}
+	})
+
+	t.Run("Empty stream", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := DebugStream(stream(), &buf); err != nil {
+			t.Fatalf("DebugStream() on empty stream returned error: %v", err)
+		}
+
+		expected := "Files to be deleted:\n\n0 files will be deleted in total\n0 B of disk space will be freed\nEND\n"
+		if got := buf.String(); got != expected {
+			t.Errorf("wrong output for empty stream\ngot:  %q\nwant: %q", got, expected)
+		}
+	})
+
+	t.Run("Interrupted scan", func(t *testing.T) {
+		files := func(yield func(scanner.FileInfo, error) bool) {
+			if yield(scanner.FileInfo{Path: "/tmp/a.log", Root: "/tmp", Size: 1024}, nil) {
+				yield(scanner.FileInfo{}, context.Canceled)
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := DebugStream(files, &buf); !errors.Is(err, context.Canceled) {
+			t.Fatalf("DebugStream() error = %v, want context.Canceled", err)
 		}
 
-		if err := Execute(files); err != nil {
-			t.Fatalf("Execute() returned error on missing file: %v", err)
+		want := "Files to be deleted:\n" +
+			"---------------------------------\nPATH: /tmp/a.log\n\n" +
+			"1 files will be deleted in total\n" +
+			"1.0 KB of disk space will be freed\n" +
+			"INTERRUPTED: the scan was stopped, the list is incomplete\n" +
+			"END\n"
+
+		if got := buf.String(); got != want {
+			t.Errorf("wrong output\ngot:  %q\nwant: %q", got, want)
 		}
 	})
 
-	t.Run("Empty files map", func(t *testing.T) {
-		files := map[string]int64{}
+	t.Run("Scan error", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := DebugStream(failingStream(errors.New("permission denied")), &buf)
+		if err == nil || !strings.Contains(err.Error(), "permission denied") {
+			t.Fatalf("DebugStream() error = %v, want scan error", err)
+		}
 
-		if err := Execute(files); err != nil {
-			t.Fatalf("Execute() on empty map returned error: %v", err)
+		if buf.Len() != 0 {
+			t.Errorf("DebugStream() wrote %q before the scan error", buf.String())
 		}
 	})
 }
 
-func TestDebugRemover(t *testing.T) {
-	t.Run("Does not remove files", func(t *testing.T) {
+func TestExecuteStream(t *testing.T) {
+	t.Run("Remove found files, keep protected", func(t *testing.T) {
 		tmpDir := t.TempDir()
 
-		files := map[string]int64{
-			filepath.Join(tmpDir, "info-1.log"): 100,
-			filepath.Join(tmpDir, "info-2.log"): 200,
+		remove := filepath.Join(tmpDir, "info-1.log")
+		keep := filepath.Join(tmpDir, "audit.log")
+
+		for _, path := range []string{remove, keep} {
+			if err := os.WriteFile(path, []byte("important"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		files := stream(
+			scanner.FileInfo{Path: remove},
+			scanner.FileInfo{Path: keep, Protected: true},
+			scanner.FileInfo{Path: filepath.Join(tmpDir, "missing.log")},
+		)
+
+		sum, err := ExecuteStream(t.Context(), files, Options{})
+		if err != nil {
+			t.Fatalf("ExecuteStream() return error: %v", err)
+		}
+
+		want := Summary{Removed: 1, RemovedSize: 0, Skipped: 2, Last: filepath.Join(tmpDir, "missing.log")}
+		if !reflect.DeepEqual(sum, want) {
+			t.Errorf("ExecuteStream() summary = %+v, want %+v", sum, want)
+		}
+
+		if _, err := os.Stat(remove); !os.IsNotExist(err) {
+			t.Errorf("File %q was not deleted", remove)
+		}
+
+		if _, err := os.Stat(keep); err != nil {
+			t.Errorf("Protected file %q was deleted", keep)
+		}
+	})
+
+	t.Run("Empty stream", func(t *testing.T) {
+		sum, err := ExecuteStream(t.Context(), stream(), Options{})
+		if err != nil {
+			t.Fatalf("ExecuteStream() on empty stream returned error: %v", err)
 		}
 
-		for path := range files {
+		if !reflect.DeepEqual(sum, Summary{}) {
+			t.Errorf("ExecuteStream() summary = %+v, want empty", sum)
+		}
+	})
+
+	t.Run("Scan error", func(t *testing.T) {
+		if _, err := ExecuteStream(t.Context(), failingStream(errors.New("permission denied")), Options{}); err == nil {
+			t.Fatal("ExecuteStream() did not return the scan error")
+		}
+	})
+
+	t.Run("Stop when cancelled", func(t *testing.T) {
+		tmpDir := t.TempDir()
+
+		first := filepath.Join(tmpDir, "info-1.log")
+		second := filepath.Join(tmpDir, "info-2.log")
+
+		for _, path := range []string{first, second} {
 			if err := os.WriteFile(path, []byte("important"), 0644); err != nil {
 				t.Fatal(err)
 			}
 		}
 
-		var buf bytes.Buffer
-		if err := DebugRemover(files, &buf); err != nil {
-			t.Fatal(err)
+		ctx, cancel := context.WithCancel(t.Context())
+
+		files := func(yield func(scanner.FileInfo, error) bool) {
+			if !yield(scanner.FileInfo{Path: first, Size: 9}, nil) {
+				return
+			}
+
+			cancel()
+
+			yield(scanner.FileInfo{Path: second, Size: 9}, nil)
 		}
 
-		for path := range files {
-			if _, err := os.Stat(path); os.IsNotExist(err) {
-				t.Fatalf("DebugRemover() deleted file %s", path)
+		sum, err := ExecuteStream(ctx, files, Options{})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("ExecuteStream() error = %v, want context.Canceled", err)
+		}
+
+		if want := (Summary{Removed: 1, RemovedSize: 9, Last: first, Interrupted: true}); !reflect.DeepEqual(sum, want) {
+			t.Errorf("ExecuteStream() summary = %+v, want %+v", sum, want)
+		}
+
+		if _, err := os.Stat(second); err != nil {
+			t.Errorf("File %q was deleted after cancellation", second)
+		}
+	})
+}
+
+func TestExecuteStreamFailures(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// A directory that is not empty cannot be removed, even by root.
+	busy := filepath.Join(tmpDir, "busy")
+	files := []string{filepath.Join(tmpDir, "a.log"), busy, filepath.Join(tmpDir, "b.log")}
+
+	if err := os.MkdirAll(filepath.Join(busy, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	create := func() {
+		for _, path := range []string{files[0], files[2]} {
+			if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+				t.Fatal(err)
 			}
 		}
+	}
+
+	list := func() iter.Seq2[scanner.FileInfo, error] {
+		infos := make([]scanner.FileInfo, len(files))
+		for i, path := range files {
+			infos[i] = scanner.FileInfo{Path: path, Size: 1}
+		}
+
+		return stream(infos...)
+	}
+
+	t.Run("Keep going", func(t *testing.T) {
+		create()
+
+		sum, err := ExecuteStream(t.Context(), list(), Options{})
+
+		var removeErr *RemoveError
+		if !errors.As(err, &removeErr) || len(removeErr.Failures) != 1 {
+			t.Fatalf("ExecuteStream() error = %v, want one failure", err)
+		}
+
+		f := removeErr.Failures[0]
+		if f.Path != busy || f.Op != "remove" || f.Errno != syscall.ENOTEMPTY && f.Errno != syscall.EEXIST {
+			t.Errorf("wrong failure %+v", f)
+		}
+
+		if !errors.Is(err, syscall.ENOTEMPTY) && !errors.Is(err, syscall.EEXIST) {
+			t.Errorf("errors.Is() does not reach the errno of %v", err)
+		}
+
+		if sum.Removed != 2 || len(sum.Failures) != 1 {
+			t.Errorf("wrong summary %+v", sum)
+		}
 	})
 
-	t.Run("Output files and size", func(t *testing.T) {
-		files := map[string]int64{
-			"/tmp/log/fake/info1.log": 1024,
-			"/tmp/log/fake/info2.log": 2048,
+	t.Run("Fail fast", func(t *testing.T) {
+		create()
+
+		sum, err := ExecuteStream(t.Context(), list(), Options{FailFast: true})
+
+		var removeErr *RemoveError
+		if !errors.As(err, &removeErr) || len(removeErr.Failures) != 1 {
+			t.Fatalf("ExecuteStream() error = %v, want one failure", err)
 		}
 
-		var buf bytes.Buffer
-		if err := DebugRemover(files, &buf); err != nil {
-			t.Fatalf("DebugRemover() error %v", err)
-		}
-
-		got := buf.String()
-		want := []string{
-			"2 files will be deleted in total",
-			"3.0 KB of disk space will be freed",
-			"Files to be deleted:",
-			"---------------------------------",
-			"PATH: /tmp/log/fake/info1.log",
-			"---------------------------------",
-			"PATH: /tmp/log/fake/info2.log",
-			"",
-			"END",
-		}
-
-		for _, w := range want {
-			if !strings.Contains(got, w) {
-				t.Errorf("output missing %q\nfull output:\n%s", w, got)
-			}
+		if sum.Removed != 1 {
+			t.Errorf("ExecuteStream() removed %d files, want 1", sum.Removed)
+		}
+
+		if _, err := os.Stat(files[2]); err != nil {
+			t.Errorf("File %q was removed after the failure", files[2])
 		}
 	})
+}
 
-	t.Run("Empty files map", func(t *testing.T) {
+func TestWriteSummary(t *testing.T) {
+	t.Run("Interrupted", func(t *testing.T) {
 		var buf bytes.Buffer
+		if err := WriteSummary(Summary{Removed: 2, RemovedSize: 3072, Skipped: 1, Last: "/tmp/b.log", Interrupted: true}, &buf); err != nil {
+			t.Fatal(err)
+		}
 
-		files := make(map[string]int64)
+		want := "Removed: 2 files, 3.0 KB\n" +
+			"Skipped: 1 files kept by protect rules or already gone\n" +
+			"Remaining: files after /tmp/b.log were not checked and are left in place\n"
 
-		if err := DebugRemover(files, &buf); err != nil {
-			t.Fatalf("DebugRemover() on empty map returned error: %v", err)
+		if got := buf.String(); got != want {
+			t.Errorf("wrong output\ngot:  %q\nwant: %q", got, want)
 		}
+	})
 
-		expected := "0 files will be deleted in total\n0 B of disk space will be freed\n\nFiles to be deleted:\n\nEND\n"
-		if got := buf.String(); got != expected {
-			t.Errorf("wrong output for empty map\ngot:  %q\nwant: %q", got, expected)
+	t.Run("Failures", func(t *testing.T) {
+		sum := Summary{
+			Removed:  1,
+			Failures: []Failure{{Path: "/srv/x.log", Op: "remove", Errno: syscall.EACCES, Err: syscall.EACCES}},
+		}
+
+		var buf bytes.Buffer
+		if err := WriteSummary(sum, &buf); err != nil {
+			t.Fatal(err)
+		}
+
+		want := "Removed: 1 files, 0 B\n" +
+			"Skipped: 0 files kept by protect rules or already gone\n" +
+			"Failed: 1 files\n" +
+			fmt.Sprintf("  remove /srv/x.log: %v (errno %d)\n", syscall.EACCES, syscall.EACCES)
+
+		if got := buf.String(); got != want {
+			t.Errorf("wrong output\ngot:  %q\nwant: %q", got, want)
 		}
 	})
 }
+
+func stream(files ...scanner.FileInfo) iter.Seq2[scanner.FileInfo, error] {
+	return func(yield func(scanner.FileInfo, error) bool) {
+		for _, f := range files {
+			if !yield(f, nil) {
+				return
+			}
+		}
+	}
+}
+
+func failingStream(err error) iter.Seq2[scanner.FileInfo, error] {
+	return func(yield func(scanner.FileInfo, error) bool) {
+		yield(scanner.FileInfo{}, err)
+	}
+}
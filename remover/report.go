@@ -0,0 +1,188 @@
+package remover
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"time"
+
+	"github.com/figurecode/files-remover/conf"
+	"github.com/figurecode/files-remover/scanner"
+)
+
+// Report is the full outcome of a dry run or a real deletion, used by the
+// structured output formats. In a dry run Files are the files to be deleted,
+// after a real deletion they are the removed ones.
+type Report struct {
+	DryRun      bool            `json:"dry_run"`
+	Count       int             `json:"count"`
+	TotalBytes  int64           `json:"total_bytes"`
+	Files       []ReportFile    `json:"files"`
+	Protected   []ReportFile    `json:"protected"`
+	Skipped     []ReportSkipped `json:"skipped"`
+	Failed      []ReportFailure `json:"failed"`
+	Interrupted bool            `json:"interrupted"`
+}
+
+type ReportFile struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	Owner   string    `json:"owner,omitempty"`
+	Pattern string    `json:"pattern"`
+	Root    string    `json:"root"`
+}
+
+// ReportSkipped is a path left alone because it could not be read or was
+// already gone.
+type ReportSkipped struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+type ReportFailure struct {
+	Path  string `json:"path"`
+	Op    string `json:"op"`
+	Errno int    `json:"errno"`
+	Error string `json:"error"`
+}
+
+func NewReport(dryRun bool) *Report {
+	return &Report{
+		DryRun:    dryRun,
+		Files:     make([]ReportFile, 0),
+		Protected: make([]ReportFile, 0),
+		Skipped:   make([]ReportSkipped, 0),
+		Failed:    make([]ReportFailure, 0),
+	}
+}
+
+// Collect fills a dry run report from the scan. An interrupted scan leaves a
+// partial report and returns the interrupt error.
+func (r *Report) Collect(files iter.Seq2[scanner.FileInfo, error]) error {
+	for f, err := range files {
+		if isInterrupt(err) {
+			r.Interrupted = true
+
+			return err
+		}
+
+		if err != nil {
+			return fmt.Errorf("traversing directories: %w", err)
+		}
+
+		switch {
+		case f.Err != nil:
+			r.Record(f, Unreadable, f.Err)
+		case f.Protected:
+			r.Record(f, Protected, nil)
+		default:
+			r.Record(f, Removed, nil)
+		}
+	}
+
+	return nil
+}
+
+// Record adds a file to the report. It matches Options.OnFile, so a real
+// deletion can fill the report as it goes.
+func (r *Report) Record(f scanner.FileInfo, outcome Outcome, err error) {
+	file := reportFile(f)
+
+	switch outcome {
+	case Removed:
+		r.Files = append(r.Files, file)
+		r.Count++
+		r.TotalBytes += f.Size
+	case Protected:
+		r.Protected = append(r.Protected, file)
+	case Gone:
+		r.Skipped = append(r.Skipped, ReportSkipped{Path: f.Path, Reason: "already gone"})
+	case Unreadable:
+		r.Skipped = append(r.Skipped, ReportSkipped{Path: f.Path, Reason: err.Error()})
+	case Failed:
+		r.Failed = append(r.Failed, reportFailure(f, err))
+	}
+}
+
+func reportFile(f scanner.FileInfo) ReportFile {
+	return ReportFile{Path: f.Path, Size: f.Size, ModTime: f.ModTime, Owner: f.Owner, Pattern: f.Pattern, Root: f.Root}
+}
+
+func reportFailure(f scanner.FileInfo, err error) ReportFailure {
+	var failure Failure
+	if !errors.As(err, &failure) {
+		failure = newFailure(f.Path, err)
+	}
+
+	return ReportFailure{
+		Path:  failure.Path,
+		Op:    failure.Op,
+		Errno: int(failure.Errno),
+		Error: failure.Err.Error(),
+	}
+}
+
+// Interrupt marks the report as partial.
+func (r *Report) Interrupt() {
+	r.Interrupted = true
+}
+
+func (r *Report) WriteJSON(out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(r)
+}
+
+// WriteCSV writes the files of the report as a table with a header row, one
+// file per row. With comma set to '\t' it writes TSV.
+func (r *Report) WriteCSV(out io.Writer, comma rune) error {
+	w := csv.NewWriter(out)
+	w.Comma = comma
+
+	if err := w.Write([]string{"path", "size", "human_size", "mtime", "owner", "pattern"}); err != nil {
+		return err
+	}
+
+	for _, f := range r.Files {
+		record := []string{
+			f.Path,
+			strconv.FormatInt(f.Size, 10),
+			humanSize(f.Size),
+			f.ModTime.Format(time.RFC3339),
+			f.Owner,
+			f.Pattern,
+		}
+
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
+
+// TemplateSample returns a report with one entry in every list, for checking
+// a user template before the run. Ranges over the lists are executed then.
+func TemplateSample() *Report {
+	r := NewReport(true)
+	r.Record(scanner.FileInfo{Path: "/sample/file"}, Removed, nil)
+	r.Record(scanner.FileInfo{Path: "/sample/protected"}, Protected, nil)
+	r.Record(scanner.FileInfo{Path: "/sample/gone"}, Gone, nil)
+	r.Record(scanner.FileInfo{Path: "/sample/failed"}, Failed, errors.New("sample"))
+
+	return r
+}
+
+// WriteTemplate renders the report with a user template, which gets the
+// Report as its data and TemplateFuncs.
+func (r *Report) WriteTemplate(out io.Writer, tmpl conf.Template) error {
+	return tmpl.Execute(out, r)
+}
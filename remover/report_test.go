@@ -0,0 +1,249 @@
+package remover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/figurecode/files-remover/scanner"
+)
+
+func TestReportCollect(t *testing.T) {
+	mtime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	files := stream(
+		scanner.FileInfo{Path: "/srv/a/1.tmp", Root: "/srv/a", Pattern: "*.tmp", Size: 1024, ModTime: mtime},
+		scanner.FileInfo{Path: "/srv/a/keep.tmp", Root: "/srv/a", Pattern: "*.tmp", Size: 10, ModTime: mtime, Protected: true},
+		scanner.FileInfo{Path: "/srv/a/locked", Root: "/srv/a", Err: syscall.EACCES},
+		scanner.FileInfo{Path: "/srv/b/2.tmp", Root: "/srv/b", Pattern: "*.tmp", Size: 2048, ModTime: mtime},
+	)
+
+	rep := NewReport(true)
+	if err := rep.Collect(files); err != nil {
+		t.Fatalf("Collect() error %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := rep.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() error %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("WriteJSON() wrote invalid JSON: %v\n%s", err, buf.String())
+	}
+
+	want := map[string]any{
+		"dry_run":     true,
+		"count":       2.0,
+		"total_bytes": 3072.0,
+		"files": []any{
+			map[string]any{"path": "/srv/a/1.tmp", "size": 1024.0, "mtime": "2025-03-01T12:00:00Z", "pattern": "*.tmp", "root": "/srv/a"},
+			map[string]any{"path": "/srv/b/2.tmp", "size": 2048.0, "mtime": "2025-03-01T12:00:00Z", "pattern": "*.tmp", "root": "/srv/b"},
+		},
+		"protected": []any{
+			map[string]any{"path": "/srv/a/keep.tmp", "size": 10.0, "mtime": "2025-03-01T12:00:00Z", "pattern": "*.tmp", "root": "/srv/a"},
+		},
+		"skipped": []any{
+			map[string]any{"path": "/srv/a/locked", "reason": syscall.EACCES.Error()},
+		},
+		"failed":      []any{},
+		"interrupted": false,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong report\ngot:  %v\nwant: %v", got, want)
+	}
+
+	t.Run("Interrupted", func(t *testing.T) {
+		files := func(yield func(scanner.FileInfo, error) bool) {
+			if yield(scanner.FileInfo{Path: "/tmp/a.log", Size: 1}, nil) {
+				yield(scanner.FileInfo{}, context.Canceled)
+			}
+		}
+
+		rep := NewReport(true)
+		if err := rep.Collect(files); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Collect() error = %v, want context.Canceled", err)
+		}
+
+		if !rep.Interrupted || rep.Count != 1 {
+			t.Errorf("wrong partial report %+v", rep)
+		}
+	})
+}
+
+func TestReportRecord(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	removed := filepath.Join(tmpDir, "a.log")
+	busy := filepath.Join(tmpDir, "busy")
+
+	if err := os.WriteFile(removed, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(busy, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := stream(
+		scanner.FileInfo{Path: removed, Size: 1},
+		scanner.FileInfo{Path: busy, Size: 1},
+		scanner.FileInfo{Path: filepath.Join(tmpDir, "missing.log"), Size: 1},
+	)
+
+	rep := NewReport(false)
+
+	_, err := ExecuteStream(t.Context(), files, Options{OnFile: rep.Record})
+
+	var removeErr *RemoveError
+	if !errors.As(err, &removeErr) {
+		t.Fatalf("ExecuteStream() error = %v, want *RemoveError", err)
+	}
+
+	if rep.DryRun || rep.Count != 1 || rep.Files[0].Path != removed {
+		t.Errorf("wrong removed files %+v", rep)
+	}
+
+	if len(rep.Skipped) != 1 || rep.Skipped[0].Reason != "already gone" {
+		t.Errorf("wrong skipped files %+v", rep.Skipped)
+	}
+
+	if len(rep.Failed) != 1 || rep.Failed[0].Path != busy || rep.Failed[0].Op != "remove" || rep.Failed[0].Errno == 0 {
+		t.Errorf("wrong failed files %+v", rep.Failed)
+	}
+}
+
+func TestReportWriteCSV(t *testing.T) {
+	mtime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	rep := NewReport(true)
+	rep.Record(scanner.FileInfo{Path: "/srv/a/1.tmp", Size: 1536, ModTime: mtime, Owner: "www", Pattern: "*.tmp"}, Removed, nil)
+	rep.Record(scanner.FileInfo{Path: "/srv/a/b,\"c\"\nd.tmp", Size: 10, ModTime: mtime, Owner: "root", Pattern: "*.tmp"}, Removed, nil)
+	rep.Record(scanner.FileInfo{Path: "/srv/a/keep.tmp", Size: 10, ModTime: mtime, Protected: true}, Protected, nil)
+
+	tests := []struct {
+		name  string
+		comma rune
+		want  string
+	}{
+		{
+			name:  "CSV",
+			comma: ',',
+			want: "path,size,human_size,mtime,owner,pattern\n" +
+				"/srv/a/1.tmp,1536,1.5 KB,2025-03-01T12:00:00Z,www,*.tmp\n" +
+				"\"/srv/a/b,\"\"c\"\"\nd.tmp\",10,10 B,2025-03-01T12:00:00Z,root,*.tmp\n",
+		},
+		{
+			name:  "TSV",
+			comma: '\t',
+			want: "path\tsize\thuman_size\tmtime\towner\tpattern\n" +
+				"/srv/a/1.tmp\t1536\t1.5 KB\t2025-03-01T12:00:00Z\twww\t*.tmp\n" +
+				"\"/srv/a/b,\"\"c\"\"\nd.tmp\"\t10\t10 B\t2025-03-01T12:00:00Z\troot\t*.tmp\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			if err := rep.WriteCSV(&buf, tt.comma); err != nil {
+				t.Fatalf("WriteCSV() error %v", err)
+			}
+
+			if buf.String() != tt.want {
+				t.Errorf("wrong table\ngot:  %q\nwant: %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestReportWriteTemplate(t *testing.T) {
+	mtime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	rep := NewReport(true)
+	rep.Record(scanner.FileInfo{Path: "/srv/a/1.tmp", Root: "/srv/a", Pattern: "*.tmp", Size: 1536, ModTime: mtime}, Removed, nil)
+	rep.Record(scanner.FileInfo{Path: "/srv/a/keep.tmp", Root: "/srv/a", Pattern: "*.tmp", Size: 10, ModTime: mtime}, Protected, nil)
+
+	tmpl := template.Must(template.New("plan").Funcs(TemplateFuncs).Parse(
+		`{{range .Files}}{{.Path}} {{humanSize .Size}} {{.ModTime.Format "2006-01-02"}} {{.Root}} {{.Pattern}}
+{{end}}kept {{len .Protected}}, total {{humanSize .TotalBytes}}`))
+
+	var buf strings.Builder
+	if err := rep.WriteTemplate(&buf, tmpl); err != nil {
+		t.Fatalf("WriteTemplate() error %v", err)
+	}
+
+	want := "/srv/a/1.tmp 1.5 KB 2025-03-01 /srv/a *.tmp\nkept 1, total 1.5 KB"
+	if buf.String() != want {
+		t.Errorf("wrong output\ngot:  %q\nwant: %q", buf.String(), want)
+	}
+}
+
+func TestReportWriteHTML(t *testing.T) {
+	now := time.Date(2025, 3, 11, 12, 0, 0, 0, time.UTC)
+
+	rep := NewReport(true)
+	rep.Record(scanner.FileInfo{Path: "/srv/a/<b>&c.tmp", Size: 1536, ModTime: now.Add(-10 * 24 * time.Hour), Pattern: "*.tmp"}, Removed, nil)
+	rep.Record(scanner.FileInfo{Path: "/srv/a/sub/d.tmp", Size: 100, ModTime: now.Add(-3 * time.Hour), Pattern: "*.tmp"}, Removed, nil)
+	rep.Record(scanner.FileInfo{Path: "/srv/a/sub/e.tmp", Size: 100, ModTime: now.Add(-time.Minute), Pattern: "*.tmp"}, Removed, nil)
+	rep.Record(scanner.FileInfo{Path: "/srv/a/locked"}, Unreadable, syscall.EACCES)
+
+	var buf strings.Builder
+	if err := rep.WriteHTML(&buf, now); err != nil {
+		t.Fatalf("WriteHTML() error %v", err)
+	}
+
+	page := buf.String()
+
+	for _, want := range []string{
+		"<h1>Cleanup plan</h1>",
+		"3 files will be deleted, 1.7 KB of disk space will be freed.",
+		`<tr><td>/srv/a/&lt;b&gt;&amp;c.tmp</td><td class="num" data-sort="1536">1.5 KB</td><td class="num" data-sort="864000">10d</td>`,
+		`<td class="num" data-sort="10800">3h</td>`,
+		`<td class="num" data-sort="60">1m</td>`,
+		`<tr><td>/srv/a</td><td class="num" data-sort="1">1</td><td class="num" data-sort="1536">1.5 KB</td></tr>`,
+		`<tr><td>/srv/a/sub</td><td class="num" data-sort="2">2</td><td class="num" data-sort="200">200 B</td></tr>`,
+		"<tr><td>/srv/a/locked</td><td>" + syscall.EACCES.Error() + "</td></tr>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page has no %q\n%s", want, page)
+		}
+	}
+
+	for _, unwanted := range []string{"<b>&c", "Failed", "interrupted"} {
+		if strings.Contains(page, unwanted) {
+			t.Errorf("page has unexpected %q", unwanted)
+		}
+	}
+}
+
+func TestTemplateSample(t *testing.T) {
+	sample := TemplateSample()
+
+	if len(sample.Files) == 0 || len(sample.Protected) == 0 || len(sample.Skipped) == 0 || len(sample.Failed) == 0 {
+		t.Fatalf("sample has an empty list %+v", sample)
+	}
+
+	ok := template.Must(template.New("ok").Funcs(TemplateFuncs).Parse(
+		`{{range .Files}}{{.Path}} {{humanSize .Size}} {{.ModTime}} {{.Owner}} {{.Pattern}} {{.Root}}{{end}}` +
+			`{{range .Skipped}}{{.Reason}}{{end}}{{range .Failed}}{{.Op}} {{.Errno}} {{.Error}}{{end}}`))
+	if err := ok.Execute(io.Discard, sample); err != nil {
+		t.Errorf("Execute() error %v", err)
+	}
+
+	bad := template.Must(template.New("bad").Parse(`{{range .Failed}}{{.Reason}}{{end}}`))
+	if err := bad.Execute(io.Discard, sample); err == nil {
+		t.Error("Execute() with a wrong field in a range has no error")
+	}
+}
@@ -0,0 +1,81 @@
+package remover
+
+import (
+	"cmp"
+	"iter"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/figurecode/files-remover/conf"
+	"github.com/figurecode/files-remover/scanner"
+)
+
+// Sorted collects the whole scan and yields the files in the order of key,
+// ties broken by path. An error ending the scan is yielded after the files
+// found before it, so an interrupted report stays ordered too.
+func Sorted(files iter.Seq2[scanner.FileInfo, error], key conf.SortKey, reverse bool) iter.Seq2[scanner.FileInfo, error] {
+	return func(yield func(scanner.FileInfo, error) bool) {
+		var found []scanner.FileInfo
+		var scanErr error
+
+		for f, err := range files {
+			if err != nil {
+				scanErr = err
+
+				break
+			}
+
+			found = append(found, f)
+		}
+
+		order := compareFiles(key, reverse)
+		slices.SortFunc(found, func(a, b scanner.FileInfo) int { return order(reportFile(a), reportFile(b)) })
+
+		for _, f := range found {
+			if !yield(f, nil) {
+				return
+			}
+		}
+
+		if scanErr != nil {
+			yield(scanner.FileInfo{}, scanErr)
+		}
+	}
+}
+
+// Sort orders the files of the report by key, by path when no key is given.
+// Skipped and failed paths are sorted by path.
+func (r *Report) Sort(key conf.SortKey, reverse bool) {
+	order := compareFiles(key, reverse)
+
+	slices.SortFunc(r.Files, order)
+	slices.SortFunc(r.Protected, order)
+	slices.SortFunc(r.Skipped, func(a, b ReportSkipped) int { return strings.Compare(a.Path, b.Path) })
+	slices.SortFunc(r.Failed, func(a, b ReportFailure) int { return strings.Compare(a.Path, b.Path) })
+}
+
+func compareFiles(key conf.SortKey, reverse bool) func(a, b ReportFile) int {
+	return func(a, b ReportFile) int {
+		var c int
+
+		switch key {
+		case conf.SortSize:
+			c = cmp.Compare(a.Size, b.Size)
+		case conf.SortMTime:
+			c = a.ModTime.Compare(b.ModTime)
+		case conf.SortDir:
+			c = strings.Compare(filepath.Dir(a.Path), filepath.Dir(b.Path))
+		}
+
+		if c == 0 {
+			c = strings.Compare(a.Path, b.Path)
+		}
+
+		if reverse {
+			return -c
+		}
+
+		return c
+	}
+}
@@ -0,0 +1,99 @@
+package remover
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/figurecode/files-remover/conf"
+	"github.com/figurecode/files-remover/scanner"
+)
+
+func TestSorted(t *testing.T) {
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	files := []scanner.FileInfo{
+		{Path: "/srv/b/x.log", Size: 30, ModTime: day.Add(2 * time.Hour)},
+		{Path: "/srv/a.log", Size: 10, ModTime: day.Add(3 * time.Hour)},
+		{Path: "/srv/b/a.log", Size: 30, ModTime: day},
+		{Path: "/srv/c.log", Size: 20, ModTime: day.Add(time.Hour)},
+	}
+
+	tests := []struct {
+		name    string
+		key     conf.SortKey
+		reverse bool
+		want    []string
+	}{
+		{name: "none", key: conf.SortNone, want: []string{"/srv/a.log", "/srv/b/a.log", "/srv/b/x.log", "/srv/c.log"}},
+		{name: "path", key: conf.SortPath, want: []string{"/srv/a.log", "/srv/b/a.log", "/srv/b/x.log", "/srv/c.log"}},
+		{name: "path reverse", key: conf.SortPath, reverse: true, want: []string{"/srv/c.log", "/srv/b/x.log", "/srv/b/a.log", "/srv/a.log"}},
+		{name: "size", key: conf.SortSize, want: []string{"/srv/a.log", "/srv/c.log", "/srv/b/a.log", "/srv/b/x.log"}},
+		{name: "size reverse", key: conf.SortSize, reverse: true, want: []string{"/srv/b/x.log", "/srv/b/a.log", "/srv/c.log", "/srv/a.log"}},
+		{name: "mtime", key: conf.SortMTime, want: []string{"/srv/b/a.log", "/srv/c.log", "/srv/b/x.log", "/srv/a.log"}},
+		{name: "dir", key: conf.SortDir, want: []string{"/srv/a.log", "/srv/c.log", "/srv/b/a.log", "/srv/b/x.log"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+
+			for f, err := range Sorted(stream(files...), tt.key, tt.reverse) {
+				if err != nil {
+					t.Fatalf("Sorted() error %v", err)
+				}
+
+				got = append(got, f.Path)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sorted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("error comes last", func(t *testing.T) {
+		files := func(yield func(scanner.FileInfo, error) bool) {
+			if yield(scanner.FileInfo{Path: "/srv/b.log"}, nil) && yield(scanner.FileInfo{Path: "/srv/a.log"}, nil) {
+				yield(scanner.FileInfo{}, context.Canceled)
+			}
+		}
+
+		var got []string
+		var last error
+
+		for f, err := range Sorted(files, conf.SortPath, false) {
+			if err != nil {
+				last = err
+
+				continue
+			}
+
+			got = append(got, f.Path)
+		}
+
+		if !reflect.DeepEqual(got, []string{"/srv/a.log", "/srv/b.log"}) || !errors.Is(last, context.Canceled) {
+			t.Errorf("Sorted() = %v, %v", got, last)
+		}
+	})
+}
+
+func TestReportSort(t *testing.T) {
+	rep := NewReport(true)
+	rep.Record(scanner.FileInfo{Path: "/srv/b.log", Size: 1}, Removed, nil)
+	rep.Record(scanner.FileInfo{Path: "/srv/a.log", Size: 2}, Removed, nil)
+	rep.Record(scanner.FileInfo{Path: "/srv/d.log"}, Gone, nil)
+	rep.Record(scanner.FileInfo{Path: "/srv/c.log"}, Gone, nil)
+
+	rep.Sort(conf.SortSize, true)
+
+	if rep.Files[0].Path != "/srv/a.log" || rep.Files[1].Path != "/srv/b.log" {
+		t.Errorf("wrong files order %+v", rep.Files)
+	}
+
+	if rep.Skipped[0].Path != "/srv/c.log" || rep.Skipped[1].Path != "/srv/d.log" {
+		t.Errorf("wrong skipped order %+v", rep.Skipped)
+	}
+}
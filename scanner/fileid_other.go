@@ -0,0 +1,21 @@
+//go:build !unix
+
+package scanner
+
+import "os"
+
+// fileID falls back to the resolved path where the stat data has no device
+// and inode numbers.
+type fileID struct {
+	dev, ino uint64
+	path     string
+}
+
+func getFileID(path string, _ os.FileInfo) fileID {
+	return fileID{path: realPath(path)}
+}
+
+// fileDevice reports no device, so WithOneFileSystem has no effect here.
+func fileDevice(os.FileInfo) (uint64, bool) {
+	return 0, false
+}
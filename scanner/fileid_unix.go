@@ -0,0 +1,31 @@
+//go:build unix
+
+package scanner
+
+import (
+	"os"
+	"syscall"
+)
+
+type fileID struct {
+	dev, ino uint64
+	path     string
+}
+
+func getFileID(path string, fInfo os.FileInfo) fileID {
+	st, ok := fInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{path: realPath(path)}
+	}
+
+	return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}
+}
+
+func fileDevice(fInfo os.FileInfo) (uint64, bool) {
+	st, ok := fInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+
+	return uint64(st.Dev), true
+}
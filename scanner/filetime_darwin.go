@@ -0,0 +1,25 @@
+package scanner
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/figurecode/files-remover/conf"
+)
+
+func fileTime(fInfo os.FileInfo, field conf.TimeField) time.Time {
+	st, ok := fInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fInfo.ModTime()
+	}
+
+	switch field {
+	case conf.AccessTime:
+		return time.Unix(st.Atimespec.Unix())
+	case conf.ChangeTime:
+		return time.Unix(st.Ctimespec.Unix())
+	}
+
+	return fInfo.ModTime()
+}
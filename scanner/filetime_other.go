@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+
+package scanner
+
+import (
+	"os"
+	"time"
+
+	"github.com/figurecode/files-remover/conf"
+)
+
+func fileTime(fInfo os.FileInfo, _ conf.TimeField) time.Time {
+	return fInfo.ModTime()
+}
@@ -0,0 +1,26 @@
+package scanner
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/figurecode/files-remover/conf"
+)
+
+// Windows has no inode change time, so ctime falls back to the creation time.
+func fileTime(fInfo os.FileInfo, field conf.TimeField) time.Time {
+	attr, ok := fInfo.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return fInfo.ModTime()
+	}
+
+	switch field {
+	case conf.AccessTime:
+		return time.Unix(0, attr.LastAccessTime.Nanoseconds())
+	case conf.ChangeTime:
+		return time.Unix(0, attr.CreationTime.Nanoseconds())
+	}
+
+	return fInfo.ModTime()
+}
@@ -0,0 +1,172 @@
+package scanner
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/figurecode/files-remover/conf"
+)
+
+// selector picks files by name: a file is selected when a positive rule
+// matches it and no negated rule does. Protect rules win over both.
+type selector struct {
+	include, exclude, protect matcher
+}
+
+func newSelector(cfg conf.Config) selector {
+	var include, exclude []conf.Rule
+
+	for _, r := range cfg.FilesName {
+		if r.Negate {
+			exclude = append(exclude, r)
+		} else {
+			include = append(include, r)
+		}
+	}
+
+	return selector{
+		include: newMatcher(include),
+		exclude: newMatcher(exclude),
+		protect: newMatcher(cfg.Protect),
+	}
+}
+
+// matcher evaluates the configured rules against a file name. Exact rules are
+// grouped by separator into lookup maps from the name to the index of its
+// rule, so long lists of names stay cheap.
+type matcher struct {
+	names    map[nameKey]map[string]int
+	rules    []conf.Rule
+	patterns []int
+	fold     bool
+}
+
+type nameKey struct {
+	sep  string
+	fold bool
+}
+
+func newMatcher(rules []conf.Rule) matcher {
+	m := matcher{names: make(map[nameKey]map[string]int), rules: rules}
+
+	for i, r := range rules {
+		m.fold = m.fold || r.Fold
+
+		if r.Mode != conf.MatchExact {
+			m.patterns = append(m.patterns, i)
+
+			continue
+		}
+
+		key, name := nameKey{sep: r.Sep, fold: r.Fold}, r.Pattern
+		if r.Fold {
+			key.sep, name = conf.FoldCase(r.Sep), conf.FoldCase(name)
+		}
+
+		if _, ok := m.names[key]; !ok {
+			m.names[key] = make(map[string]int)
+		}
+
+		if _, ok := m.names[key][name]; !ok {
+			m.names[key][name] = i
+		}
+	}
+
+	return m
+}
+
+func (m matcher) match(curentFileName string) bool {
+	_, ok := m.find(curentFileName)
+
+	return ok
+}
+
+// find returns the pattern of the first rule, in the order given, matching
+// the file name.
+func (m matcher) find(curentFileName string) (string, bool) {
+	folded := curentFileName
+	if m.fold {
+		folded = conf.FoldCase(curentFileName)
+	}
+
+	first := len(m.rules)
+
+	for key, names := range m.names {
+		name := curentFileName
+		if key.fold {
+			name = folded
+		}
+
+		if i, ok := lookup(name, names, key.sep); ok {
+			first = min(first, i)
+		}
+	}
+
+	for _, i := range m.patterns {
+		if i > first {
+			break
+		}
+
+		r := m.rules[i]
+
+		switch r.Mode {
+		case conf.MatchGlob:
+			name := curentFileName
+			if r.Fold {
+				name = folded
+			}
+
+			if matchGlob(name, r.Globs) {
+				first = i
+			}
+		case conf.MatchRegexp:
+			if r.Regexp.MatchString(curentFileName) {
+				first = i
+			}
+		}
+
+		if first == i {
+			break
+		}
+	}
+
+	if first == len(m.rules) {
+		return "", false
+	}
+
+	return m.rules[first].Pattern, true
+}
+
+func matchGlob(curentFileName string, globs []string) bool {
+	for _, g := range globs {
+		if ok, _ := filepath.Match(g, curentFileName); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookup finds the file name, or with a separator one of its parts without
+// extension, in the names map and returns the lowest rule index stored for
+// it.
+func lookup(curentFileName string, filesSearchNames map[string]int, fileNameSep string) (int, bool) {
+	if fileNameSep == "" {
+		i, ok := filesSearchNames[curentFileName]
+
+		return i, ok
+	}
+
+	first, found := 0, false
+
+	parts := strings.Split(curentFileName, fileNameSep)
+	for _, part := range parts {
+		cleanPart := strings.TrimSuffix(part, filepath.Ext(part))
+
+		if i, ok := filesSearchNames[cleanPart]; ok && (!found || i < first) {
+			first, found = i, true
+		}
+	}
+
+	return first, found
+}
@@ -0,0 +1,104 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type mountEntry struct {
+	Major, Minor uint32
+	Point        string
+	Type         string
+}
+
+// parseMountInfo reads the mount table in the /proc/self/mountinfo format.
+func parseMountInfo(r io.Reader) ([]mountEntry, error) {
+	var mounts []mountEntry
+
+	sc := bufio.NewScanner(r)
+
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		sep := -1
+
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+
+				break
+			}
+		}
+
+		if len(fields) < 5 || sep < 0 || sep+1 >= len(fields) {
+			return nil, fmt.Errorf("bad mountinfo line %q", sc.Text())
+		}
+
+		major, minor, ok := strings.Cut(fields[2], ":")
+		maj, err1 := strconv.ParseUint(major, 10, 32)
+		mnr, err2 := strconv.ParseUint(minor, 10, 32)
+
+		if !ok || err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("bad device %q in mountinfo", fields[2])
+		}
+
+		mounts = append(mounts, mountEntry{
+			Major: uint32(maj),
+			Minor: uint32(mnr),
+			Point: unescapeMountPoint(fields[4]),
+			Type:  fields[sep+1],
+		})
+	}
+
+	return mounts, sc.Err()
+}
+
+// unescapeMountPoint decodes the octal escapes like \040 used for spaces and
+// other special characters in mount points.
+func unescapeMountPoint(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+
+				continue
+			}
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// matchFSType reports whether a filesystem type matches one of the patterns,
+// either as a whole or by its main type, so "fuse" matches "fuse.sshfs".
+func matchFSType(patterns []string, fsType string) bool {
+	main, _, _ := strings.Cut(fsType, ".")
+
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, fsType); ok {
+			return true
+		}
+
+		if ok, _ := filepath.Match(p, main); ok {
+			return true
+		}
+	}
+
+	return false
+}
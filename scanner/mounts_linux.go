@@ -0,0 +1,41 @@
+package scanner
+
+import (
+	"os"
+)
+
+// skippedDevices returns the devices of the mounts whose filesystem type
+// matches one of the patterns, mapped to that type.
+func skippedDevices(patterns []string) (map[uint64]string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mounts, err := parseMountInfo(f)
+	if err != nil {
+		return nil, err
+	}
+
+	devs := make(map[uint64]string)
+
+	for _, m := range mounts {
+		if matchFSType(patterns, m.Type) {
+			devs[mkdev(m.Major, m.Minor)] = m.Type
+		}
+	}
+
+	return devs, nil
+}
+
+// mkdev encodes a device number the way the Linux kernel reports it in
+// stat st_dev.
+func mkdev(major, minor uint32) uint64 {
+	dev := uint64(major&0x00000fff) << 8
+	dev |= uint64(major&0xfffff000) << 32
+	dev |= uint64(minor&0x000000ff) << 0
+	dev |= uint64(minor&0xffffff00) << 12
+
+	return dev
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package scanner
+
+import "errors"
+
+func skippedDevices([]string) (map[uint64]string, error) {
+	return nil, errors.New("skipping filesystem types is only supported on Linux")
+}
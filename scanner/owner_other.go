@@ -0,0 +1,10 @@
+//go:build !unix
+
+package scanner
+
+import "os"
+
+// fileOwner reports no owner where the stat data has no uid.
+func fileOwner(os.FileInfo) string {
+	return ""
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package scanner
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// owners caches user names by uid, a tree usually has only a few owners.
+var owners sync.Map
+
+// fileOwner returns the name of the file owner, or its uid when the user is
+// not known to the system.
+func fileOwner(fInfo os.FileInfo) string {
+	st, ok := fInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+
+	uid := strconv.FormatUint(uint64(st.Uid), 10)
+
+	if name, ok := owners.Load(uid); ok {
+		return name.(string)
+	}
+
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+
+	owners.Store(uid, name)
+
+	return name
+}
@@ -0,0 +1,164 @@
+package scanner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// dirQueue holds the directories waiting to be read by the parallel walk.
+// pending counts the directories queued or being read, so the walk is over
+// when it drops to zero.
+type dirQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	dirs    []string
+	pending int
+	err     error
+}
+
+// walkParallel walks root with cfg.Workers goroutines, each reading one
+// directory at a time. Entries go through the same visit as the sequential
+// walk, so the result does not depend on the worker count.
+func (w *walker) walkParallel(root string) error {
+	q := &dirQueue{dirs: []string{root}, pending: 1}
+	q.cond = sync.NewCond(&q.mu)
+
+	// Workers hand found files over to this goroutine, which passes them on
+	// one at a time.
+	emit := w.emit
+	found := make(chan FileInfo, w.cfg.Workers)
+	stop := make(chan struct{})
+
+	w.emit = func(f FileInfo) bool {
+		select {
+		case found <- f:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+	defer func() { w.emit = emit }()
+
+	var wg sync.WaitGroup
+
+	for range w.cfg.Workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				dir, ok := q.next()
+				if !ok {
+					return
+				}
+
+				subdirs, err := w.readDir(root, dir)
+				q.done(subdirs, err)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(found)
+	}()
+
+	stopped := false
+
+	for f := range found {
+		if stopped {
+			continue
+		}
+
+		if err := w.ctx.Err(); err != nil {
+			stopped = true
+			close(stop)
+			q.abort(err)
+
+			continue
+		}
+
+		if !emit(f) {
+			stopped = true
+			close(stop)
+			q.abort(errStopped)
+		}
+	}
+
+	return q.err
+}
+
+func (q *dirQueue) next() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.dirs) == 0 && q.pending > 0 && q.err == nil {
+		q.cond.Wait()
+	}
+
+	if q.pending == 0 || q.err != nil {
+		return "", false
+	}
+
+	dir := q.dirs[len(q.dirs)-1]
+	q.dirs = q.dirs[:len(q.dirs)-1]
+
+	return dir, true
+}
+
+func (q *dirQueue) done(subdirs []string, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err != nil && q.err == nil {
+		q.err = err
+	}
+
+	q.dirs = append(q.dirs, subdirs...)
+	q.pending += len(subdirs) - 1
+	q.cond.Broadcast()
+}
+
+func (q *dirQueue) abort(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.err == nil {
+		q.err = err
+	}
+
+	q.cond.Broadcast()
+}
+
+// readDir visits the entries of dir and returns the subdirectories the walk
+// has to descend into.
+func (w *walker) readDir(root, dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, w.skip(root, dir, err)
+	}
+
+	var subdirs []string
+
+	for _, d := range entries {
+		path := filepath.Join(dir, d.Name())
+
+		err := w.visit(root, path, d)
+		if errors.Is(err, filepath.SkipDir) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if d.IsDir() {
+			subdirs = append(subdirs, path)
+		}
+	}
+
+	return subdirs, nil
+}
@@ -1,17 +1,66 @@
 package scanner
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"iter"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/figurecode/files-remover/conf"
 )
 
 type FoundFiles map[string]int64
 
+// Result is the outcome of a scan. Protected holds files that matched a
+// pattern but were kept by a protect rule. Roots holds the totals of the
+// files found in every scanned root, in scan order. Skipped holds the paths
+// that could not be read, in scan order.
+type Result struct {
+	Files     FoundFiles
+	Protected FoundFiles
+	Roots     []RootTotal
+	Skipped   []SkippedPath
+}
+
+type RootTotal struct {
+	Root  string
+	Files int
+	Size  int64
+}
+
+// FileInfo describes a file found by Scan. Pattern is the pattern that
+// selected the file. Owner is the user name of the file owner, empty where the
+// system does not report it. Protected files matched a pattern but are kept by a
+// protect rule. Err is set for a path skipped by the OnError policy because
+// it could not be read; only Path and Root are filled then.
+type FileInfo struct {
+	Path      string
+	Root      string
+	Pattern   string
+	Size      int64
+	ModTime   time.Time
+	Owner     string
+	Protected bool
+	Err       error
+}
+
+// SkippedPath is a directory or file skipped because it could not be read.
+type SkippedPath struct {
+	Path string
+	Err  error
+}
+
+// errStopped aborts the walk when the consumer of Scan stops early.
+var errStopped = errors.New("scan stopped")
+
 func ResolvePath(path string) (string, error) {
 	if filepath.IsAbs(path) {
 		return path, nil
@@ -25,72 +74,741 @@ func ResolvePath(path string) (string, error) {
 	return filepath.Join(wd, path), nil
 }
 
-func ScanDir(cfg conf.Config) (FoundFiles, error) {
-	info, err := os.Stat(cfg.Dir)
+// ResolveRoots turns the -d values into absolute directories. Values with glob
+// meta characters are expanded to the directories they match. Duplicate roots
+// and roots nested in another root are dropped. No values means the current
+// directory.
+func ResolveRoots(patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		patterns = []string{""}
+	}
+
+	roots := make([]string, 0, len(patterns))
+
+	for _, p := range patterns {
+		path, err := ResolvePath(p)
+		if err != nil {
+			return nil, err
+		}
+
+		path = filepath.Clean(path)
+
+		if !strings.ContainsAny(path, `*?[`) {
+			roots = append(roots, path)
+
+			continue
+		}
+
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("bad directory pattern %q: %w", p, err)
+		}
+
+		found := false
+
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.IsDir() {
+				roots = append(roots, m)
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("no directory matches %q", p)
+		}
+	}
+
+	return dedupeRoots(roots), nil
+}
+
+// dedupeRoots drops repeated roots and roots inside another root, comparing
+// the paths with symlinks resolved.
+func dedupeRoots(roots []string) []string {
+	keys := make([]string, len(roots))
+
+	for i, r := range roots {
+		keys[i] = realPath(r)
+	}
+
+	res := make([]string, 0, len(roots))
+
+	for i, r := range roots {
+		covered := false
+
+		for j := range roots {
+			if (keys[i] == keys[j] && j < i) || (keys[i] != keys[j] && isWithin(keys[j], keys[i])) {
+				covered = true
+
+				break
+			}
+		}
+
+		if !covered {
+			res = append(res, r)
+		}
+	}
+
+	return res
+}
+
+// realPath returns path with symlinks resolved, or the cleaned path when it
+// cannot be resolved.
+func realPath(path string) string {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+
+	return filepath.Clean(path)
+}
+
+// isWithin reports whether path is strictly inside root.
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ScanDir runs Scan and collects every found file into a Result. It is a
+// helper for callers that need the whole scan at once, like the tests; the
+// program itself streams with Scan and keeps memory bounded.
+func ScanDir(ctx context.Context, cfg conf.Config) (Result, error) {
+	res := Result{Files: make(FoundFiles), Protected: make(FoundFiles)}
+	index := make(map[string]int)
+
+	for _, root := range dedupeRoots(cfg.Dirs) {
+		index[root] = len(res.Roots)
+		res.Roots = append(res.Roots, RootTotal{Root: root})
+	}
+
+	for f, err := range Scan(ctx, cfg) {
+		if err != nil {
+			return res, err
+		}
+
+		if f.Err != nil {
+			res.Skipped = append(res.Skipped, SkippedPath{Path: f.Path, Err: f.Err})
+
+			continue
+		}
+
+		if f.Protected {
+			res.Protected[f.Path] = f.Size
+
+			continue
+		}
+
+		res.Files[f.Path] = f.Size
+
+		total := &res.Roots[index[f.Root]]
+		total.Files++
+		total.Size += f.Size
+	}
+
+	return res, nil
+}
+
+// Scan walks the roots and yields every found file as soon as it is checked,
+// so callers can report or remove files while the walk goes on. A failed or
+// cancelled scan ends with the error and a zero FileInfo.
+func Scan(ctx context.Context, cfg conf.Config) iter.Seq2[FileInfo, error] {
+	return func(yield func(FileInfo, error) bool) {
+		w := walker{
+			ctx: ctx,
+			cfg: cfg,
+			sel: newSelector(cfg),
+			emit: func(f FileInfo) bool {
+				if f.Err != nil && cfg.OnError == conf.ErrorWarn {
+					fmt.Fprintf(cfg.ErrStream, "Warning: skipped %s: %v\n", f.Path, f.Err)
+				}
+
+				return yield(f, nil)
+			},
+		}
+
+		if err := w.run(); err != nil && !errors.Is(err, errStopped) {
+			yield(FileInfo{}, err)
+		}
+	}
+}
+
+func (w *walker) run() error {
+	if len(w.cfg.SkipFSTypes) > 0 {
+		devs, err := skippedDevices(w.cfg.SkipFSTypes)
+		if err != nil {
+			return err
+		}
+
+		w.skipDevs = devs
+	}
+
+	roots := dedupeRoots(w.cfg.Dirs)
+	w.seen = make(map[fileID]bool)
+
+	if w.cfg.FromFile != "" || w.cfg.FromStdin {
+		return w.scanList(roots)
+	}
+
+	for _, root := range roots {
+		if err := w.walkRoot(root); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type walker struct {
+	ctx  context.Context
+	cfg  conf.Config
+	sel  selector
+	emit func(FileInfo) bool
+
+	rootDev  uint64
+	skipDevs map[uint64]string
+	listed   map[string]bool
+	seen     map[fileID]bool
+}
+
+func (w *walker) walkRoot(root string) error {
+	info, err := os.Stat(root)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if !info.IsDir() {
-		return nil, fmt.Errorf("%q is not a directory", cfg.Dir)
+		return fmt.Errorf("%q is not a directory", root)
+	}
+
+	w.rootDev, _ = fileDevice(info)
+
+	if fsType, ok := w.skipDevs[w.rootDev]; ok {
+		fmt.Fprintf(w.cfg.ErrStream, "Warning: %s is on a skipped %s filesystem\n", root, fsType)
+
+		return nil
+	}
+
+	// Following links stays sequential, so a directory reached through several
+	// paths is always reported under the same one.
+	switch {
+	case w.cfg.FollowSymlinks:
+		return w.walkFollow(root, root, info)
+	case w.cfg.Workers > 1:
+		return w.walkParallel(root)
+	default:
+		return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				if err := w.skip(root, path, err); err != nil {
+					return err
+				}
+
+				if d != nil && d.IsDir() {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			if path == root {
+				return nil
+			}
+
+			return w.visit(root, path, d)
+		})
+	}
+}
+
+// skip applies the OnError policy to a path that cannot be read. It returns
+// an error when the scan has to stop.
+func (w *walker) skip(root, path string, err error) error {
+	if w.cfg.OnError == conf.ErrorAbort {
+		return err
+	}
+
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+
+	if !w.emit(FileInfo{Path: path, Root: root, Err: err}) {
+		return errStopped
+	}
+
+	return nil
+}
+
+// visit handles one walked entry. For a directory it returns filepath.SkipDir
+// when the walk must not descend into it.
+func (w *walker) visit(root, path string, d os.DirEntry) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+
+	if w.excluded(root, path, d.Name()) {
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+
+		return nil
+	}
+
+	depth := pathDepth(root, path)
+
+	if d.IsDir() {
+		if w.cfg.MaxDepth > 0 && depth >= w.cfg.MaxDepth || w.foreignDevice(d) {
+			return filepath.SkipDir
+		}
+
+		return nil
+	}
+
+	if depth < w.cfg.MinDepth {
+		return nil
+	}
+
+	return w.checkFile(root, path, d)
+}
+
+// foreignDevice reports whether an entry lies on another filesystem than the
+// root with WithOneFileSystem, or on a filesystem of a skipped type.
+func (w *walker) foreignDevice(d os.DirEntry) bool {
+	if !w.cfg.OneFileSystem && len(w.skipDevs) == 0 {
+		return false
+	}
+
+	info, err := d.Info()
+	if err != nil {
+		return false
+	}
+
+	dev, ok := fileDevice(info)
+	if !ok {
+		return false
+	}
+
+	if _, skip := w.skipDevs[dev]; skip {
+		return true
+	}
+
+	return w.cfg.OneFileSystem && dev != w.rootDev
+}
+
+// walkFollow walks dir like filepath.WalkDir, but also descends into
+// symlinked directories. Directories are told apart by device and inode, so a
+// directory reached again through a link, a loop or another root is not
+// walked twice.
+func (w *walker) walkFollow(root, dir string, info os.FileInfo) error {
+	id := getFileID(dir, info)
+	if w.seen[id] {
+		return nil
+	}
+
+	w.seen[id] = true
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return w.skip(root, dir, err)
 	}
 
-	files := make(FoundFiles)
+	for _, d := range entries {
+		path := filepath.Join(dir, d.Name())
+
+		if d.Type()&os.ModeSymlink != 0 {
+			if target, ok := w.linkedDir(root, path); ok {
+				d = fs.FileInfoToDirEntry(target)
+			}
+		}
+
+		err := w.visit(root, path, d)
+		if errors.Is(err, filepath.SkipDir) {
+			continue
+		}
 
-	err = filepath.WalkDir(cfg.Dir, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
 		if !d.IsDir() {
-			return checkFile(cfg, path, d, files)
+			continue
 		}
 
-		if len(cfg.ExcDirs) > 0 && slices.Contains(cfg.ExcDirs, d.Name()) {
-			return filepath.SkipDir
+		info, err := d.Info()
+		if err != nil {
+			if err := w.skip(root, path, err); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := w.walkFollow(root, path, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// linkedDir returns the target of a symlink when it is a directory the walk
+// may follow. With StayInRoot links leading outside the root are refused.
+func (w *walker) linkedDir(root, path string) (os.FileInfo, bool) {
+	target, err := os.Stat(path)
+	if err != nil || !target.IsDir() {
+		return nil, false
+	}
+
+	if w.cfg.StayInRoot {
+		realRoot, real := realPath(root), realPath(path)
+
+		if real != realRoot && !isWithin(realRoot, real) {
+			fmt.Fprintf(w.cfg.ErrStream, "Warning: symlink %q leads outside %s, not followed\n", path, root)
+
+			return nil, false
+		}
+	}
+
+	return target, true
+}
+
+// scanList checks the candidate paths from FromFile or InStream instead of
+// walking the roots. Paths go through the same exclusions, depth limits and
+// filters as during a walk, and paths outside the roots are rejected.
+func (w *walker) scanList(roots []string) error {
+	r := w.cfg.InStream
+
+	if w.cfg.FromFile != "" {
+		f, err := os.Open(w.cfg.FromFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	realRoots := make([]string, len(roots))
+	rootDevs := make([]uint64, len(roots))
+
+	for i, root := range roots {
+		info, err := os.Stat(root)
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			return fmt.Errorf("%q is not a directory", root)
+		}
+
+		realRoots[i] = realPath(root)
+		rootDevs[i], _ = fileDevice(info)
+	}
+
+	w.listed = make(map[string]bool)
+
+	sc := bufio.NewScanner(r)
+	if w.cfg.NullSep {
+		sc.Split(scanNull)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	lines, readErr := readLines(sc, done)
+
+	for {
+		var line string
+		var ok bool
+
+		select {
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+		case line, ok = <-lines:
+		}
+
+		if !ok {
+			return <-readErr
 		}
 
+		if !w.cfg.NullSep {
+			line = strings.TrimSuffix(line, "\r")
+		}
+
+		if line == "" {
+			continue
+		}
+
+		path, err := ResolvePath(line)
+		if err != nil {
+			return err
+		}
+
+		path = filepath.Clean(path)
+
+		i := slices.IndexFunc(roots, func(root string) bool { return isWithin(root, path) })
+		if i < 0 || !w.insideReal(realRoots[i], path) {
+			fmt.Fprintf(w.cfg.ErrStream, "Warning: %q is outside the search directories, skipped\n", line)
+
+			continue
+		}
+
+		w.rootDev = rootDevs[i]
+
+		if err := w.checkPath(roots[i], path); err != nil {
+			return err
+		}
+	}
+}
+
+// readLines reads the list in the background, so an interrupt is noticed
+// while the input is idle. A read blocked on a pipe cannot be cancelled, the
+// goroutine ends with the next line or the end of input once done is closed.
+func readLines(sc *bufio.Scanner, done <-chan struct{}) (<-chan string, <-chan error) {
+	lines := make(chan string)
+	readErr := make(chan error, 1)
+
+	go func() {
+		defer close(lines)
+
+		for sc.Scan() {
+			select {
+			case lines <- sc.Text():
+			case <-done:
+				return
+			}
+		}
+
+		readErr <- sc.Err()
+	}()
+
+	return lines, readErr
+}
+
+// insideReal reports whether the directory holding path, with symlinks
+// resolved, is still inside the root, so a listed path cannot reach outside
+// the root through a symlinked directory.
+func (w *walker) insideReal(realRoot, path string) bool {
+	dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return true
+	}
+
+	return dir == realRoot || isWithin(realRoot, dir)
+}
+
+func (w *walker) checkPath(root, path string) error {
+	depth := pathDepth(root, path)
+
+	if depth < w.cfg.MinDepth || w.cfg.MaxDepth > 0 && depth > w.cfg.MaxDepth {
+		return nil
+	}
+
+	for p := path; p != root; p = filepath.Dir(p) {
+		if w.excluded(root, p, filepath.Base(p)) {
+			return nil
+		}
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil || info.IsDir() {
+		return nil
+	}
+
+	d := fs.FileInfoToDirEntry(info)
+	if w.listed[path] || w.foreignDevice(d) {
 		return nil
-	})
+	}
+
+	w.listed[path] = true
+
+	return w.checkFile(root, path, d)
+}
 
-	return files, err
+func scanNull(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
 }
 
-func checkFile(cfg conf.Config, path string, d os.DirEntry, files FoundFiles) error {
+// pathDepth returns the number of path elements between root and path, so
+// entries directly inside root have depth 1.
+func pathDepth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+func (w *walker) excluded(root, path, name string) bool {
+	if len(w.cfg.Exclusions) == 0 {
+		return false
+	}
+
+	var rel, abs []string
+
+	for _, e := range w.cfg.Exclusions {
+		switch {
+		case e.Name:
+			if ok, _ := filepath.Match(e.Segments[0], name); ok {
+				return true
+			}
+
+			continue
+		case e.Abs:
+			if abs == nil {
+				abs = strings.Split(filepath.ToSlash(path), "/")
+			}
+
+			if matchSegments(e.Segments, abs) {
+				return true
+			}
+		default:
+			if rel == nil {
+				r, err := filepath.Rel(root, path)
+				if err != nil {
+					return false
+				}
+
+				rel = strings.Split(filepath.ToSlash(r), "/")
+			}
+
+			if matchSegments(e.Segments, rel) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// matchSegments matches a path split by "/" against pattern segments, where a
+// "**" segment matches zero or more path segments.
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+
+		if ok, _ := filepath.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+
+		pattern, segments = pattern[1:], segments[1:]
+	}
+
+	return len(segments) == 0
+}
+
+func (w *walker) checkFile(root, path string, d os.DirEntry) error {
 	_, curentFileName := filepath.Split(path)
 
-	if !match(curentFileName, cfg.FilesName, cfg.FileNameSep) {
+	pattern, ok := w.sel.include.find(curentFileName)
+	if !ok || w.sel.exclude.match(curentFileName) {
 		return nil
 	}
 
 	fInfo, err := d.Info()
 
+	// A file gone between listing and checking was never an error with the
+	// abort policy, so only the tolerant policies record it.
 	if err != nil {
+		if w.cfg.OnError == conf.ErrorAbort {
+			return nil
+		}
+
+		return w.skip(root, path, err)
+	}
+
+	if !matchType(w.cfg.Types, path, fInfo) || !matchSize(w.cfg, fInfo.Size()) || !matchTime(w.cfg, fInfo) {
 		return nil
 	}
 
-	files[path] = fInfo.Size()
+	f := FileInfo{
+		Path:      path,
+		Root:      root,
+		Pattern:   pattern,
+		Size:      fInfo.Size(),
+		ModTime:   fInfo.ModTime(),
+		Owner:     fileOwner(fInfo),
+		Protected: w.sel.protect.match(curentFileName),
+	}
+
+	if !w.emit(f) {
+		return errStopped
+	}
 
 	return nil
 }
 
-func match(curentFileName string, filesSearchNames map[string]bool, fileNameSep string) bool {
-	if fileNameSep == "" {
-		if _, ok := filesSearchNames[curentFileName]; ok {
+func matchType(types conf.FileType, path string, fInfo os.FileInfo) bool {
+	if types == 0 {
+		return true
+	}
+
+	mode := fInfo.Mode()
+
+	switch {
+	case mode.IsRegular():
+		return types&conf.TypeRegular != 0 || (types&conf.TypeEmpty != 0 && fInfo.Size() == 0)
+	case mode&os.ModeSymlink != 0:
+		if types&conf.TypeSymlink != 0 {
 			return true
 		}
 
+		if types&conf.TypeBrokenLink == 0 {
+			return false
+		}
+
+		_, err := os.Stat(path)
+
+		return errors.Is(err, fs.ErrNotExist)
+	case mode&os.ModeNamedPipe != 0:
+		return types&conf.TypeFIFO != 0
+	case mode&os.ModeSocket != 0:
+		return types&conf.TypeSocket != 0
+	}
+
+	return false
+}
+
+func matchSize(cfg conf.Config, size int64) bool {
+	if size < cfg.MinSize {
 		return false
 	}
 
-	parts := strings.Split(curentFileName, fileNameSep)
-	for _, part := range parts {
-		cleanPart := strings.TrimSuffix(part, filepath.Ext(part))
+	return cfg.MaxSize < 0 || size <= cfg.MaxSize
+}
 
-		if _, ok := filesSearchNames[cleanPart]; ok {
-			return true
-		}
+func matchTime(cfg conf.Config, fInfo os.FileInfo) bool {
+	if cfg.OlderThan.IsZero() && cfg.NewerThan.IsZero() {
+		return true
 	}
 
-	return false
+	t := fileTime(fInfo, cfg.TimeField)
+
+	if !cfg.OlderThan.IsZero() && !t.Before(cfg.OlderThan) {
+		return false
+	}
+
+	if !cfg.NewerThan.IsZero() && !t.After(cfg.NewerThan) {
+		return false
+	}
+
+	return true
 }
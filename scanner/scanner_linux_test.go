@@ -0,0 +1,70 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/figurecode/files-remover/conf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanDirFileSystems(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	createFiles(t, tmpDir, map[string]int64{
+		"a.tmp":     100,
+		"sub/b.tmp": 200,
+	})
+
+	info, err := os.Stat(tmpDir)
+	assert.NoError(t, err)
+
+	dev, _ := fileDevice(info)
+
+	f, err := os.Open("/proc/self/mountinfo")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	mounts, err := parseMountInfo(f)
+	assert.NoError(t, err)
+
+	fsType := ""
+
+	for _, m := range mounts {
+		if mkdev(m.Major, m.Minor) == dev {
+			fsType = m.Type
+		}
+	}
+
+	if fsType == "" {
+		t.Skip("the temporary directory device is not in the mount table")
+	}
+
+	t.Run("one file system", func(t *testing.T) {
+		cfg, err := conf.New([]string{tmpDir}, []string{"glob:*.tmp"}, conf.WithOneFileSystem(true))
+		assert.NoError(t, err)
+
+		res, err := ScanDir(t.Context(), cfg)
+		assert.NoError(t, err)
+
+		assert.Equal(t, FoundFiles{
+			filepath.Join(tmpDir, "a.tmp"):     100,
+			filepath.Join(tmpDir, "sub/b.tmp"): 200,
+		}, res.Files)
+	})
+
+	t.Run("skip the root filesystem type", func(t *testing.T) {
+		errStream := &bytes.Buffer{}
+
+		cfg, err := conf.New([]string{tmpDir}, []string{"glob:*.tmp"}, conf.WithSkipFSTypes(fsType), conf.WithErrStream(errStream))
+		assert.NoError(t, err)
+
+		res, err := ScanDir(t.Context(), cfg)
+		assert.NoError(t, err)
+
+		assert.Empty(t, res.Files)
+		assert.Contains(t, errStream.String(), "is on a skipped "+fsType+" filesystem")
+	})
+}
@@ -1,9 +1,17 @@
 package scanner
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/figurecode/files-remover/conf"
 	"github.com/stretchr/testify/assert"
@@ -69,6 +77,59 @@ func TestResolvePath(t *testing.T) {
 	}
 }
 
+func TestResolveRoots(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	createFiles(t, tmpDir, map[string]int64{
+		"srv/a/tmp/x":   1,
+		"srv/b/tmp/x":   1,
+		"srv/c/data/x":  1,
+		"srv/d/tmp":     1,
+		"var/log/x":     1,
+		"var/log/sub/x": 1,
+	})
+	assert.NoError(t, os.Symlink(filepath.Join(tmpDir, "var/log"), filepath.Join(tmpDir, "logs")))
+
+	t.Run("expand globs", func(t *testing.T) {
+		roots, err := ResolveRoots([]string{filepath.Join(tmpDir, "srv/*/tmp")})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(tmpDir, "srv/a/tmp"), filepath.Join(tmpDir, "srv/b/tmp")}, roots)
+	})
+
+	t.Run("drop duplicate and nested roots", func(t *testing.T) {
+		roots, err := ResolveRoots([]string{
+			filepath.Join(tmpDir, "var/log/sub"),
+			filepath.Join(tmpDir, "var/log"),
+			filepath.Join(tmpDir, "var/log/"),
+			filepath.Join(tmpDir, "logs"),
+			filepath.Join(tmpDir, "srv/a/tmp"),
+			filepath.Join(tmpDir, "srv/*/tmp"),
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			filepath.Join(tmpDir, "var/log"),
+			filepath.Join(tmpDir, "srv/a/tmp"),
+			filepath.Join(tmpDir, "srv/b/tmp"),
+		}, roots)
+	})
+
+	t.Run("current directory by default", func(t *testing.T) {
+		wd, _ := os.Getwd()
+		roots, err := ResolveRoots(nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{wd}, roots)
+	})
+
+	t.Run("glob without directories", func(t *testing.T) {
+		_, err := ResolveRoots([]string{filepath.Join(tmpDir, "srv/*/nothing")})
+
+		assert.Error(t, err)
+	})
+}
+
 func TestScanDir(t *testing.T) {
 	t.Run("search by part of file name, separator specified", func(t *testing.T) {
 		tmpDir := t.TempDir()
@@ -83,14 +144,16 @@ func TestScanDir(t *testing.T) {
 		})
 
 		cfg, err := conf.New(
-			tmpDir,
+			[]string{tmpDir},
 			[]string{"hash", "hash"},
 			conf.WithFileNameSep("-"),
 		)
 		assert.NoError(t, err)
 
-		files, err := ScanDir(cfg)
+		res, err := ScanDir(t.Context(), cfg)
 		assert.NoError(t, err)
+
+		files := res.Files
 		assert.Len(t, files, 3)
 
 		expected := map[string]int64{
@@ -119,14 +182,16 @@ func TestScanDir(t *testing.T) {
 		})
 
 		cfg, err := conf.New(
-			tmpDir,
+			[]string{tmpDir},
 			[]string{"hash-part1-otherpart"},
 			conf.WithFileNameSep("."),
 		)
 		assert.NoError(t, err)
 
-		files, err := ScanDir(cfg)
+		res, err := ScanDir(t.Context(), cfg)
 		assert.NoError(t, err)
+
+		files := res.Files
 		assert.Len(t, files, 2)
 
 		expected := map[string]int64{
@@ -154,13 +219,15 @@ func TestScanDir(t *testing.T) {
 		})
 
 		cfg, err := conf.New(
-			tmpDir,
+			[]string{tmpDir},
 			[]string{"exact-match.pdf"},
 		)
 		assert.NoError(t, err)
 
-		files, err := ScanDir(cfg)
+		res, err := ScanDir(t.Context(), cfg)
 		assert.NoError(t, err)
+
+		files := res.Files
 		assert.Len(t, files, 2)
 
 		expected := map[string]int64{
@@ -188,15 +255,17 @@ func TestScanDir(t *testing.T) {
 		})
 
 		cfg, err := conf.New(
-			tmpDir,
+			[]string{tmpDir},
 			[]string{"exact", "match"},
 			conf.WithExcludeDir("node_modules"),
 			conf.WithFileNameSep("-"),
 		)
 		assert.NoError(t, err)
 
-		files, err := ScanDir(cfg)
+		res, err := ScanDir(t.Context(), cfg)
 		assert.NoError(t, err)
+
+		files := res.Files
 		assert.Len(t, files, 1)
 
 		expected := map[string]int64{
@@ -223,46 +292,1153 @@ func TestScanDir(t *testing.T) {
 	})
 }
 
+func TestScanDirGlob(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	createFiles(t, tmpDir, map[string]int64{
+		"backup-2025-01-01.tar.gz":     1024,
+		"backup-2025-01-02.zip":        2048,
+		"backup.tar.gz":                100,
+		"logs/session_1234.tmp":        512,
+		"logs/session_12345.tmp":       256,
+		"logs/backup-2024-12-31.tar":   300,
+		"cache/backup-2025-02-01.zip":  4096,
+		"cache/session_abcd.tmp.bak":   10,
+		"cache/session_abcd.tmp":       20,
+		"ignored/backup-2025-03-01.gz": 30,
+	})
+
+	cfg, err := conf.New(
+		[]string{tmpDir},
+		[]string{"backup-*.{tar.gz,zip}", "session_????.tmp"},
+		conf.WithMatchMode("glob"),
+		conf.WithFileNameSep("-"),
+	)
+	assert.NoError(t, err)
+
+	res, err := ScanDir(t.Context(), cfg)
+	assert.NoError(t, err)
+
+	files := res.Files
+
+	expected := FoundFiles{
+		filepath.Join(tmpDir, "backup-2025-01-01.tar.gz"):    1024,
+		filepath.Join(tmpDir, "backup-2025-01-02.zip"):       2048,
+		filepath.Join(tmpDir, "logs/session_1234.tmp"):       512,
+		filepath.Join(tmpDir, "cache/backup-2025-02-01.zip"): 4096,
+		filepath.Join(tmpDir, "cache/session_abcd.tmp"):      20,
+	}
+
+	assert.Equal(t, expected, files)
+
+	t.Run("negated class", func(t *testing.T) {
+		tmpDir := t.TempDir()
+
+		createFiles(t, tmpDir, map[string]int64{"s_1.tmp": 1, "s_a.tmp": 2, "s_!.tmp": 3})
+
+		cfg, err := conf.New([]string{tmpDir}, []string{"s_[!0-9].tmp"}, conf.WithMatchMode("glob"))
+		assert.NoError(t, err)
+
+		res, err := ScanDir(t.Context(), cfg)
+		assert.NoError(t, err)
+
+		assert.Equal(t, FoundFiles{
+			filepath.Join(tmpDir, "s_a.tmp"): 2,
+			filepath.Join(tmpDir, "s_!.tmp"): 3,
+		}, res.Files)
+	})
+}
+
+func TestScanDirRegexp(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	createFiles(t, tmpDir, map[string]int64{
+		"debug-2025.log":       1024,
+		"debug-12345.log":      2048,
+		"logs/debug-0001.log":  512,
+		"logs/debug-0001.log1": 256,
+		"logs/debug-abcd.log":  128,
+	})
+
+	cfg, err := conf.New([]string{tmpDir}, []string{`^debug-\d{4}\.log$`}, conf.WithMatchMode("regex"))
+	assert.NoError(t, err)
+
+	res, err := ScanDir(t.Context(), cfg)
+	assert.NoError(t, err)
+
+	files := res.Files
+
+	expected := FoundFiles{
+		filepath.Join(tmpDir, "debug-2025.log"):      1024,
+		filepath.Join(tmpDir, "logs/debug-0001.log"): 512,
+	}
+
+	assert.Equal(t, expected, files)
+}
+
+func TestScanDirMixedRules(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	createFiles(t, tmpDir, map[string]int64{
+		".DS_Store":            10,
+		"docs/.DS_Store":       20,
+		"docs/.DS_Store.bak":   30,
+		"cache-main.db":        40,
+		"logs/cache-old":       50,
+		"temp_2025.log":        60,
+		"temp-2025.log":        70,
+		"logs/session-1.log":   80,
+		"logs/session_1.log":   90,
+		"logs/debug-12.log":    100,
+		"logs/debug-12345.log": 110,
+	})
+
+	cfg, err := conf.New(
+		[]string{tmpDir},
+		[]string{"exact:.DS_Store", "glob:cache-*", "sep=_:temp", "session", `re:^debug-\d{2}\.`},
+		conf.WithFileNameSep("-"),
+	)
+	assert.NoError(t, err)
+
+	res, err := ScanDir(t.Context(), cfg)
+	assert.NoError(t, err)
+
+	files := res.Files
+
+	expected := FoundFiles{
+		filepath.Join(tmpDir, ".DS_Store"):          10,
+		filepath.Join(tmpDir, "docs/.DS_Store"):     20,
+		filepath.Join(tmpDir, "cache-main.db"):      40,
+		filepath.Join(tmpDir, "logs/cache-old"):     50,
+		filepath.Join(tmpDir, "temp_2025.log"):      60,
+		filepath.Join(tmpDir, "logs/session-1.log"): 80,
+		filepath.Join(tmpDir, "logs/debug-12.log"):  100,
+	}
+
+	assert.Equal(t, expected, files)
+}
+
+func TestScanDirNegateAndProtect(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	createFiles(t, tmpDir, map[string]int64{
+		"app.log":            10,
+		"app-keep.log":       20,
+		"audit-2025.log":     30,
+		"logs/worker.log":    40,
+		"logs/audit-old.log": 50,
+		"logs/worker.txt":    60,
+		"audit-2025.txt":     70,
+	})
+
+	cfg, err := conf.New(
+		[]string{tmpDir},
+		[]string{"*.log", "!*-keep.log"},
+		conf.WithMatchMode("glob"),
+		conf.WithProtect([]string{"audit-*"}),
+	)
+	assert.NoError(t, err)
+
+	res, err := ScanDir(t.Context(), cfg)
+	assert.NoError(t, err)
+
+	assert.Equal(t, FoundFiles{
+		filepath.Join(tmpDir, "app.log"):         10,
+		filepath.Join(tmpDir, "logs/worker.log"): 40,
+	}, res.Files)
+	assert.Equal(t, FoundFiles{
+		filepath.Join(tmpDir, "audit-2025.log"):     30,
+		filepath.Join(tmpDir, "logs/audit-old.log"): 50,
+	}, res.Protected)
+}
+
+func TestScanDirCaseInsensitive(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	createFiles(t, tmpDir, map[string]int64{
+		"Backup.ZIP":           10,
+		"old/BACKUP.zip":       20,
+		"old/backup.zip.part":  30,
+		"ОТЧЁТ-2025.LOG":       40,
+		"отчёт-2024.log":       50,
+		"Straße_1.tmp":         60,
+		"STRASSE_1.tmp":        70,
+		"ΟΔΟΣ.txt":             80,
+		"Ärger-Cache-1.DB":     90,
+		"ärger-cache-2.db":     100,
+		"reports/Отчёт_q1.csv": 110,
+	})
+
+	cfg, err := conf.New(
+		[]string{tmpDir},
+		[]string{
+			"backup.zip",
+			"glob:отчёт-*.log",
+			`re:^straße_\d\.TMP$`,
+			"exact:οδος.TXT",
+			"sep=-:ÄRGER",
+			"sep=_:отчёт",
+		},
+		conf.WithCaseInsensitive(true),
+	)
+	assert.NoError(t, err)
+
+	res, err := ScanDir(t.Context(), cfg)
+	assert.NoError(t, err)
+
+	expected := FoundFiles{
+		filepath.Join(tmpDir, "Backup.ZIP"):           10,
+		filepath.Join(tmpDir, "old/BACKUP.zip"):       20,
+		filepath.Join(tmpDir, "ОТЧЁТ-2025.LOG"):       40,
+		filepath.Join(tmpDir, "отчёт-2024.log"):       50,
+		filepath.Join(tmpDir, "Straße_1.tmp"):         60,
+		filepath.Join(tmpDir, "ΟΔΟΣ.txt"):             80,
+		filepath.Join(tmpDir, "Ärger-Cache-1.DB"):     90,
+		filepath.Join(tmpDir, "ärger-cache-2.db"):     100,
+		filepath.Join(tmpDir, "reports/Отчёт_q1.csv"): 110,
+	}
+
+	assert.Equal(t, expected, res.Files)
+
+	t.Run("case-sensitive by default", func(t *testing.T) {
+		cfg, err := conf.New([]string{tmpDir}, []string{"backup.zip", "glob:отчёт-*.log"})
+		assert.NoError(t, err)
+
+		res, err := ScanDir(t.Context(), cfg)
+		assert.NoError(t, err)
+
+		assert.Equal(t, FoundFiles{filepath.Join(tmpDir, "отчёт-2024.log"): 50}, res.Files)
+	})
+}
+
+func TestScanDirAge(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tmpDir := t.TempDir()
+	ages := map[string]time.Duration{
+		"backup-1.zip":      1 * day,
+		"backup-10.zip":     10 * day,
+		"old/backup-30.zip": 30 * day,
+		"backup-90.zip":     90 * day,
+	}
+
+	createFiles(t, tmpDir, map[string]int64{
+		"backup-1.zip":      10,
+		"backup-10.zip":     20,
+		"old/backup-30.zip": 30,
+		"backup-90.zip":     40,
+	})
+
+	for name, age := range ages {
+		mtime := now.Add(-age)
+		atime := now.Add(-100 * day)
+		assert.NoError(t, os.Chtimes(filepath.Join(tmpDir, name), atime, mtime))
+	}
+
+	tests := []struct {
+		name string
+		opts []conf.Option
+		want []string
+	}{
+		{
+			name: "older than",
+			opts: []conf.Option{conf.WithOlderThan("1w")},
+			want: []string{"backup-10.zip", "old/backup-30.zip", "backup-90.zip"},
+		},
+		{
+			name: "newer than",
+			opts: []conf.Option{conf.WithNewerThan("15d")},
+			want: []string{"backup-1.zip", "backup-10.zip"},
+		},
+		{
+			name: "range",
+			opts: []conf.Option{conf.WithOlderThan("7d"), conf.WithNewerThan("60d")},
+			want: []string{"backup-10.zip", "old/backup-30.zip"},
+		},
+		{
+			name: "absolute date",
+			opts: []conf.Option{conf.WithOlderThan("2025-05-01T00:00:00Z")},
+			want: []string{"backup-90.zip"},
+		},
+		{
+			name: "access time",
+			opts: []conf.Option{conf.WithOlderThan("50d"), conf.WithTimeField("atime")},
+			want: []string{"backup-1.zip", "backup-10.zip", "old/backup-30.zip", "backup-90.zip"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]conf.Option{conf.WithMatchMode("glob"), conf.WithClock(func() time.Time { return now })}, tt.opts...)
+
+			cfg, err := conf.New([]string{tmpDir}, []string{"backup-*.zip"}, opts...)
+			assert.NoError(t, err)
+
+			res, err := ScanDir(t.Context(), cfg)
+			assert.NoError(t, err)
+
+			got := make([]string, 0, len(res.Files))
+			for path := range res.Files {
+				rel, _ := filepath.Rel(tmpDir, path)
+				got = append(got, rel)
+			}
+
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func TestScanDirSize(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	createFiles(t, tmpDir, map[string]int64{
+		"core.1":      0,
+		"core.2":      512,
+		"core.3":      10 * 1024,
+		"dumps/core4": 3 << 20,
+	})
+
+	tests := []struct {
+		name string
+		opts []conf.Option
+		want []string
+	}{
+		{
+			name: "min size",
+			opts: []conf.Option{conf.WithMinSize("10K")},
+			want: []string{"core.3", "dumps/core4"},
+		},
+		{
+			name: "max size",
+			opts: []conf.Option{conf.WithMaxSize("0.5K")},
+			want: []string{"core.1", "core.2"},
+		},
+		{
+			name: "only empty files",
+			opts: []conf.Option{conf.WithMaxSize("0")},
+			want: []string{"core.1"},
+		},
+		{
+			name: "range",
+			opts: []conf.Option{conf.WithMinSize("1"), conf.WithMaxSize("1.5M")},
+			want: []string{"core.2", "core.3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := conf.New([]string{tmpDir}, []string{"glob:core*"}, tt.opts...)
+			assert.NoError(t, err)
+
+			res, err := ScanDir(t.Context(), cfg)
+			assert.NoError(t, err)
+
+			got := make([]string, 0, len(res.Files))
+			for path := range res.Files {
+				rel, _ := filepath.Rel(tmpDir, path)
+				got = append(got, rel)
+			}
+
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func TestScanDirPathExclusions(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	createFiles(t, tmpDir, map[string]int64{
+		"app.log":                         1,
+		"journal/app.log":                 2,
+		"nginx/journal/app.log":           3,
+		".snapshots/app.log":              4,
+		"vendor/app.log":                  5,
+		"src/vendor/pkg/app.log":          6,
+		"projects/one/cache/app.log":      7,
+		"projects/one/cache.log/app.log":  8,
+		"projects/one/deep/cache/app.log": 9,
+		"keep/app.log":                    10,
+	})
+
+	cfg, err := conf.New(
+		[]string{tmpDir},
+		[]string{"app.log"},
+		conf.WithErrStream(&bytes.Buffer{}),
+		conf.WithExcludeDir(filepath.Join(tmpDir, "journal")+",.snapshots,**/vendor/**,projects/*/cache,keep/app.log"),
+	)
+	assert.NoError(t, err)
+
+	res, err := ScanDir(t.Context(), cfg)
+	assert.NoError(t, err)
+
+	got := make([]string, 0, len(res.Files))
+	for path := range res.Files {
+		rel, _ := filepath.Rel(tmpDir, path)
+		got = append(got, rel)
+	}
+
+	assert.ElementsMatch(t, []string{
+		"app.log",
+		"nginx/journal/app.log",
+		"projects/one/cache.log/app.log",
+		"projects/one/deep/cache/app.log",
+	}, got)
+}
+
+func TestScanDirDepth(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	createFiles(t, tmpDir, map[string]int64{
+		"job.tmp":             1,
+		"a/job.tmp":           2,
+		"a/b/job.tmp":         3,
+		"a/b/c/job.tmp":       4,
+		"x/y/z/job.tmp":       5,
+		"x/y/z/deeper/j2.tmp": 6,
+	})
+
+	tests := []struct {
+		name string
+		opts []conf.Option
+		want []string
+	}{
+		{
+			name: "top level only",
+			opts: []conf.Option{conf.WithMaxDepth(1)},
+			want: []string{"job.tmp"},
+		},
+		{
+			name: "max depth",
+			opts: []conf.Option{conf.WithMaxDepth(2)},
+			want: []string{"job.tmp", "a/job.tmp"},
+		},
+		{
+			name: "min depth",
+			opts: []conf.Option{conf.WithMinDepth(4)},
+			want: []string{"a/b/c/job.tmp", "x/y/z/job.tmp", "x/y/z/deeper/j2.tmp"},
+		},
+		{
+			name: "range",
+			opts: []conf.Option{conf.WithMinDepth(2), conf.WithMaxDepth(3)},
+			want: []string{"a/job.tmp", "a/b/job.tmp"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := conf.New([]string{tmpDir}, []string{"glob:*.tmp"}, tt.opts...)
+			assert.NoError(t, err)
+
+			res, err := ScanDir(t.Context(), cfg)
+			assert.NoError(t, err)
+
+			got := make([]string, 0, len(res.Files))
+			for path := range res.Files {
+				rel, _ := filepath.Rel(tmpDir, path)
+				got = append(got, rel)
+			}
+
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func TestScanDirMultipleRoots(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	createFiles(t, tmpDir, map[string]int64{
+		"var/log/app.tmp":         100,
+		"var/log/nested/app.tmp":  200,
+		"srv/a/tmp/job.tmp":       300,
+		"srv/a/tmp/skip/job.tmp":  400,
+		"srv/b/tmp/job.tmp":       500,
+		"srv/b/tmp/other.txt":     600,
+		"home/u/.cache/x/old.tmp": 700,
+	})
+
+	roots, err := ResolveRoots([]string{
+		filepath.Join(tmpDir, "var/log"),
+		filepath.Join(tmpDir, "var/log/nested"),
+		filepath.Join(tmpDir, "srv/*/tmp"),
+	})
+	assert.NoError(t, err)
+
+	cfg, err := conf.New(
+		roots,
+		[]string{"glob:*.tmp"},
+		conf.WithExcludeDir(filepath.Join(tmpDir, "srv/a/tmp/skip")),
+	)
+	assert.NoError(t, err)
+
+	res, err := ScanDir(t.Context(), cfg)
+	assert.NoError(t, err)
+
+	assert.Equal(t, FoundFiles{
+		filepath.Join(tmpDir, "var/log/app.tmp"):        100,
+		filepath.Join(tmpDir, "var/log/nested/app.tmp"): 200,
+		filepath.Join(tmpDir, "srv/a/tmp/job.tmp"):      300,
+		filepath.Join(tmpDir, "srv/b/tmp/job.tmp"):      500,
+	}, res.Files)
+	assert.Equal(t, []RootTotal{
+		{Root: filepath.Join(tmpDir, "var/log"), Files: 2, Size: 300},
+		{Root: filepath.Join(tmpDir, "srv/a/tmp"), Files: 1, Size: 300},
+		{Root: filepath.Join(tmpDir, "srv/b/tmp"), Files: 1, Size: 500},
+	}, res.Roots)
+
+	t.Run("overlapping roots from config", func(t *testing.T) {
+		cfg, err := conf.New(
+			[]string{filepath.Join(tmpDir, "var/log/nested"), filepath.Join(tmpDir, "var/log")},
+			[]string{"glob:*.tmp"},
+		)
+		assert.NoError(t, err)
+
+		res, err := ScanDir(t.Context(), cfg)
+		assert.NoError(t, err)
+
+		assert.Len(t, res.Files, 2)
+		assert.Equal(t, []RootTotal{{Root: filepath.Join(tmpDir, "var/log"), Files: 2, Size: 300}}, res.Roots)
+	})
+}
+
+func TestScanDirPathList(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := filepath.Join(tmpDir, "root")
+
+	createFiles(t, tmpDir, map[string]int64{
+		"root/a.tmp":           100,
+		"root/sub/b.tmp":       200,
+		"root/skip/c.tmp":      300,
+		"root/d.txt":           400,
+		"root/deep/er/e.tmp":   500,
+		"outside/f.tmp":        600,
+		"outside/linked/g.tmp": 700,
+	})
+	assert.NoError(t, os.Symlink(filepath.Join(tmpDir, "outside/linked"), filepath.Join(root, "link")))
+
+	paths := []string{
+		filepath.Join(root, "a.tmp"),
+		filepath.Join(root, "sub/b.tmp"),
+		filepath.Join(root, "skip/c.tmp"),
+		filepath.Join(root, "d.txt"),
+		filepath.Join(root, "deep/er/e.tmp"),
+		filepath.Join(root, "missing.tmp"),
+		filepath.Join(root, "sub"),
+		filepath.Join(root, "a.tmp"),
+		filepath.Join(tmpDir, "outside/f.tmp"),
+		filepath.Join(root, "../outside/f.tmp"),
+		filepath.Join(root, "link/g.tmp"),
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		opts  []conf.Option
+	}{
+		{
+			name:  "newline separated",
+			input: strings.Join(paths, "\r\n") + "\n\n",
+		},
+		{
+			name:  "nul separated",
+			input: strings.Join(paths, "\x00") + "\x00",
+			opts:  []conf.Option{conf.WithNullSep(true)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errStream := &bytes.Buffer{}
+			opts := append([]conf.Option{
+				conf.WithFromStdin(true),
+				conf.WithInStream(strings.NewReader(tt.input)),
+				conf.WithErrStream(errStream),
+				conf.WithExcludeDir("skip"),
+				conf.WithMaxDepth(2),
+			}, tt.opts...)
+
+			cfg, err := conf.New([]string{root}, []string{"glob:*.tmp"}, opts...)
+			assert.NoError(t, err)
+
+			res, err := ScanDir(t.Context(), cfg)
+			assert.NoError(t, err)
+
+			assert.Equal(t, FoundFiles{
+				filepath.Join(root, "a.tmp"):     100,
+				filepath.Join(root, "sub/b.tmp"): 200,
+			}, res.Files)
+			assert.Equal(t, []RootTotal{{Root: root, Files: 2, Size: 300}}, res.Roots)
+			assert.Equal(t, 3, strings.Count(errStream.String(), "is outside the search directories"))
+		})
+	}
+
+	t.Run("from file", func(t *testing.T) {
+		list := filepath.Join(tmpDir, "list.txt")
+		assert.NoError(t, os.WriteFile(list, []byte(strings.Join(paths, "\n")), 0o644))
+
+		cfg, err := conf.New([]string{root}, []string{"glob:*.tmp"}, conf.WithFromFile(list), conf.WithErrStream(io.Discard))
+		assert.NoError(t, err)
+
+		res, err := ScanDir(t.Context(), cfg)
+		assert.NoError(t, err)
+
+		assert.Len(t, res.Files, 4)
+		assert.NotContains(t, res.Files, filepath.Join(tmpDir, "outside/f.tmp"))
+	})
+
+	t.Run("cancelled on idle input", func(t *testing.T) {
+		pr, pw := io.Pipe()
+		t.Cleanup(func() { _ = pw.Close() })
+
+		go func() {
+			_, _ = io.WriteString(pw, paths[0]+"\n")
+		}()
+
+		cfg, err := conf.New([]string{root}, []string{"glob:*.tmp"}, conf.WithFromStdin(true), conf.WithInStream(pr))
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+
+		var found []string
+		var scanErr error
+
+		for f, err := range Scan(ctx, cfg) {
+			if err != nil {
+				scanErr = err
+
+				break
+			}
+
+			found = append(found, f.Path)
+			cancel()
+		}
+
+		assert.Equal(t, []string{paths[0]}, found)
+		assert.ErrorIs(t, scanErr, context.Canceled)
+	})
+
+	t.Run("missing list file", func(t *testing.T) {
+		cfg, err := conf.New([]string{root}, []string{"glob:*.tmp"}, conf.WithFromFile(filepath.Join(tmpDir, "none")))
+		assert.NoError(t, err)
+
+		_, err = ScanDir(t.Context(), cfg)
+		assert.Error(t, err)
+	})
+}
+
+func TestScanDirFollowSymlinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := filepath.Join(tmpDir, "deploy")
+
+	createFiles(t, tmpDir, map[string]int64{
+		"deploy/releases/2025/app.log":     100,
+		"deploy/releases/2025/sub/job.log": 200,
+		"deploy/app.log":                   300,
+		"shared/cache.log":                 400,
+	})
+	assert.NoError(t, os.Symlink(filepath.Join(root, "releases/2025"), filepath.Join(root, "current")))
+	assert.NoError(t, os.Symlink(root, filepath.Join(root, "releases/2025/sub/loop")))
+	assert.NoError(t, os.Symlink(filepath.Join(root, "releases"), filepath.Join(root, "releases/2025/up")))
+	assert.NoError(t, os.Symlink(filepath.Join(tmpDir, "shared"), filepath.Join(root, "shared")))
+
+	tests := []struct {
+		name string
+		opts []conf.Option
+		want []string
+		warn bool
+	}{
+		{
+			name: "links are not followed by default",
+			want: []string{"app.log", "releases/2025/app.log", "releases/2025/sub/job.log"},
+		},
+		{
+			name: "follow links, every directory once",
+			opts: []conf.Option{conf.WithFollowSymlinks(true)},
+			want: []string{"app.log", "current/app.log", "current/sub/job.log", "shared/cache.log"},
+		},
+		{
+			name: "follow links inside root only",
+			opts: []conf.Option{conf.WithFollowSymlinks(true), conf.WithStayInRoot(true)},
+			want: []string{"app.log", "current/app.log", "current/sub/job.log"},
+			warn: true,
+		},
+		{
+			name: "depth counts through links",
+			opts: []conf.Option{conf.WithFollowSymlinks(true), conf.WithMaxDepth(2)},
+			want: []string{"app.log", "current/app.log", "shared/cache.log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errStream := &bytes.Buffer{}
+			opts := append([]conf.Option{conf.WithErrStream(errStream)}, tt.opts...)
+
+			cfg, err := conf.New([]string{root}, []string{"glob:*.log"}, opts...)
+			assert.NoError(t, err)
+
+			res, err := ScanDir(t.Context(), cfg)
+			assert.NoError(t, err)
+
+			got := make([]string, 0, len(res.Files))
+			for path := range res.Files {
+				rel, _ := filepath.Rel(root, path)
+				got = append(got, rel)
+			}
+
+			assert.ElementsMatch(t, tt.want, got)
+			assert.Equal(t, tt.warn, strings.Contains(errStream.String(), "leads outside"))
+		})
+	}
+
+	t.Run("directory linked from another root", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		a, b := filepath.Join(tmpDir, "a"), filepath.Join(tmpDir, "b")
+
+		createFiles(t, tmpDir, map[string]int64{"a/1.tmp": 1, "b/2.tmp": 2, "b/sub/3.tmp": 3})
+		assert.NoError(t, os.Symlink("../b", filepath.Join(a, "link")))
+
+		cfg, err := conf.New([]string{a, b}, []string{"glob:*.tmp"}, conf.WithFollowSymlinks(true))
+		assert.NoError(t, err)
+
+		res, err := ScanDir(t.Context(), cfg)
+		assert.NoError(t, err)
+
+		assert.Equal(t, FoundFiles{
+			filepath.Join(a, "1.tmp"):          1,
+			filepath.Join(a, "link/2.tmp"):     2,
+			filepath.Join(a, "link/sub/3.tmp"): 3,
+		}, res.Files)
+		assert.Equal(t, []RootTotal{{Root: a, Files: 3, Size: 6}, {Root: b}}, res.Roots)
+	})
+}
+
+func Test_parseMountInfo(t *testing.T) {
+	input := `22 1 259:2 / / rw,relatime shared:1 - ext4 /dev/nvme0n1p2 rw
+23 22 0:21 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw
+45 22 0:40 / /mnt/my\040share rw,relatime - nfs4 server:/share rw,vers=4.2
+46 22 0:41 / /home/u/remote rw,nosuid,nodev shared:30 master:2 - fuse.sshfs u@host: rw
+`
+
+	mounts, err := parseMountInfo(strings.NewReader(input))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []mountEntry{
+		{Major: 259, Minor: 2, Point: "/", Type: "ext4"},
+		{Major: 0, Minor: 21, Point: "/proc", Type: "proc"},
+		{Major: 0, Minor: 40, Point: "/mnt/my share", Type: "nfs4"},
+		{Major: 0, Minor: 41, Point: "/home/u/remote", Type: "fuse.sshfs"},
+	}, mounts)
+
+	_, err = parseMountInfo(strings.NewReader("22 1 259:2 / / rw\n"))
+	assert.Error(t, err)
+}
+
+func Test_matchFSType(t *testing.T) {
+	patterns := []string{"nfs*", "fuse", "tmpfs"}
+
+	assert.True(t, matchFSType(patterns, "nfs"))
+	assert.True(t, matchFSType(patterns, "nfs4"))
+	assert.True(t, matchFSType(patterns, "fuse.sshfs"))
+	assert.True(t, matchFSType(patterns, "tmpfs"))
+	assert.False(t, matchFSType(patterns, "ext4"))
+	assert.False(t, matchFSType(patterns, "fuseblk"))
+}
+
+func TestScan(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	createFiles(t, tmpDir, map[string]int64{
+		"a/report.tmp": 100,
+		"a/audit.tmp":  200,
+		"b/core.1":     300,
+		"b/other.txt":  400,
+	})
+
+	mtime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, os.Chtimes(filepath.Join(tmpDir, "a/report.tmp"), mtime, mtime))
+
+	cfg, err := conf.New(
+		[]string{filepath.Join(tmpDir, "a"), filepath.Join(tmpDir, "b")},
+		[]string{"glob:*.tmp", `re:^core\.\d+$`},
+		conf.WithProtect([]string{"audit.tmp"}),
+	)
+	assert.NoError(t, err)
+
+	var got []FileInfo
+
+	for f, err := range Scan(t.Context(), cfg) {
+		assert.NoError(t, err)
+
+		got = append(got, f)
+	}
+
+	assert.Len(t, got, 3)
+	assert.Equal(t, FileInfo{
+		Path:      filepath.Join(tmpDir, "a/audit.tmp"),
+		Root:      filepath.Join(tmpDir, "a"),
+		Pattern:   "*.tmp",
+		Size:      200,
+		ModTime:   got[0].ModTime,
+		Owner:     got[0].Owner,
+		Protected: true,
+	}, got[0])
+	assert.Equal(t, "*.tmp", got[1].Pattern)
+	assert.True(t, got[1].ModTime.Equal(mtime))
+	assert.Equal(t, FileInfo{
+		Path:    filepath.Join(tmpDir, "b/core.1"),
+		Root:    filepath.Join(tmpDir, "b"),
+		Pattern: `^core\.\d+$`,
+		Size:    300,
+		ModTime: got[2].ModTime,
+		Owner:   got[2].Owner,
+	}, got[2])
+
+	t.Run("stop early", func(t *testing.T) {
+		createFiles(t, tmpDir, generateTree(3, 2, 5))
+
+		for _, workers := range []int{1, 4} {
+			cfg, err := conf.New([]string{tmpDir}, []string{"glob:*.tmp"}, conf.WithWorkers(workers))
+			assert.NoError(t, err)
+
+			n := 0
+
+			for _, err := range Scan(t.Context(), cfg) {
+				assert.NoError(t, err)
+
+				if n++; n == 3 {
+					break
+				}
+			}
+
+			assert.Equal(t, 3, n)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		for _, workers := range []int{1, 4} {
+			t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+				ctx, cancel := context.WithCancel(t.Context())
+				defer cancel()
+
+				cfg, err := conf.New([]string{tmpDir}, []string{"glob:*.tmp"}, conf.WithWorkers(workers))
+				assert.NoError(t, err)
+
+				n := 0
+
+				var last error
+
+				for _, err := range Scan(ctx, cfg) {
+					if err != nil {
+						last = err
+
+						continue
+					}
+
+					if n++; n == 2 {
+						cancel()
+					}
+				}
+
+				assert.ErrorIs(t, last, context.Canceled)
+				assert.Equal(t, 2, n)
+			})
+		}
+	})
+
+	t.Run("error comes last", func(t *testing.T) {
+		cfg, err := conf.New([]string{filepath.Join(tmpDir, "a"), filepath.Join(tmpDir, "none")}, []string{"glob:*.tmp"})
+		assert.NoError(t, err)
+
+		var errs []error
+
+		files := 0
+
+		for f, err := range Scan(t.Context(), cfg) {
+			if err != nil {
+				errs = append(errs, err)
+				assert.Zero(t, f)
+
+				continue
+			}
+
+			files++
+		}
+
+		assert.Equal(t, 2, files)
+		assert.Len(t, errs, 1)
+	})
+}
+
+func TestScanDirParallel(t *testing.T) {
+	tmpDir := t.TempDir()
+	createFiles(t, tmpDir, generateTree(4, 3, 5))
+
+	tests := []struct {
+		name string
+		opts []conf.Option
+	}{
+		{name: "whole tree"},
+		{name: "exclusions", opts: []conf.Option{conf.WithExcludeDir("d1,d2/d0,**/f3.tmp")}},
+		{name: "depth limits", opts: []conf.Option{conf.WithMinDepth(2), conf.WithMaxDepth(3)}},
+		{name: "protect", opts: []conf.Option{conf.WithProtect([]string{"f0.tmp"})}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := conf.New([]string{tmpDir}, []string{"glob:*.tmp"}, tt.opts...)
+			assert.NoError(t, err)
+
+			want, err := ScanDir(t.Context(), cfg)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, want.Files)
+
+			for _, workers := range []int{2, 8} {
+				cfg, err := conf.New([]string{tmpDir}, []string{"glob:*.tmp"}, append(tt.opts, conf.WithWorkers(workers))...)
+				assert.NoError(t, err)
+
+				got, err := ScanDir(t.Context(), cfg)
+				assert.NoError(t, err)
+				assert.Equal(t, want, got)
+			}
+		})
+	}
+
+	t.Run("missing root", func(t *testing.T) {
+		cfg, err := conf.New([]string{filepath.Join(tmpDir, "none")}, []string{"glob:*.tmp"}, conf.WithWorkers(4))
+		assert.NoError(t, err)
+
+		_, err = ScanDir(t.Context(), cfg)
+		assert.Error(t, err)
+	})
+}
+
+func TestWalkerSkip(t *testing.T) {
+	tmpDir := t.TempDir()
+	gone := filepath.Join(tmpDir, "gone")
+
+	for _, policy := range []string{"abort", "skip"} {
+		t.Run(policy, func(t *testing.T) {
+			cfg, err := conf.New([]string{tmpDir}, []string{"glob:*.tmp"}, conf.WithOnError(policy))
+			assert.NoError(t, err)
+
+			var got []FileInfo
+
+			w := walker{ctx: t.Context(), cfg: cfg, seen: make(map[fileID]bool), emit: func(f FileInfo) bool {
+				got = append(got, f)
+
+				return true
+			}}
+
+			info, err := os.Stat(tmpDir)
+			assert.NoError(t, err)
+
+			errFollow := w.walkFollow(tmpDir, gone, info)
+			_, err = w.readDir(tmpDir, gone)
+
+			if policy == "abort" {
+				assert.ErrorIs(t, err, fs.ErrNotExist)
+				assert.ErrorIs(t, errFollow, fs.ErrNotExist)
+				assert.Empty(t, got)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NoError(t, errFollow)
+			assert.Len(t, got, 2)
+
+			for _, f := range got {
+				assert.Equal(t, gone, f.Path)
+				assert.Equal(t, tmpDir, f.Root)
+				assert.ErrorIs(t, f.Err, fs.ErrNotExist)
+
+				var pathErr *fs.PathError
+				assert.False(t, errors.As(f.Err, &pathErr), "the path is not repeated in the error")
+			}
+		})
+	}
+}
+
+func BenchmarkScanDir(b *testing.B) {
+	tmpDir := b.TempDir()
+	createFiles(b, tmpDir, generateTree(10, 3, 10))
+
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			cfg, err := conf.New([]string{tmpDir}, []string{"glob:*.tmp"}, conf.WithWorkers(workers))
+			assert.NoError(b, err)
+
+			for b.Loop() {
+				if _, err := ScanDir(b.Context(), cfg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// generateTree returns a tree of empty files for createFiles: width
+// directories on every level down to depth, with files in each of them.
+func generateTree(width, depth, files int) map[string]int64 {
+	tree := make(map[string]int64)
+
+	var fill func(dir string, level int)
+
+	fill = func(dir string, level int) {
+		for i := range files {
+			tree[filepath.Join(dir, fmt.Sprintf("f%d.tmp", i))] = 0
+		}
+
+		if level == depth {
+			return
+		}
+
+		for i := range width {
+			fill(filepath.Join(dir, fmt.Sprintf("d%d", i)), level+1)
+		}
+	}
+
+	fill("", 0)
+
+	return tree
+}
+
+func Test_pathDepth(t *testing.T) {
+	assert.Equal(t, 0, pathDepth("/var/log", "/var/log"))
+	assert.Equal(t, 1, pathDepth("/var/log", "/var/log/syslog"))
+	assert.Equal(t, 3, pathDepth("/var/log", "/var/log/nginx/old/access.log"))
+	assert.Equal(t, 1, pathDepth("/", "/tmp"))
+	assert.Equal(t, 2, pathDepth("/tmp/", "/tmp/a/b"))
+}
+
+func Test_matchSegments(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "journal", path: "journal", want: true},
+		{pattern: "journal", path: "nginx/journal", want: false},
+		{pattern: "projects/*/cache", path: "projects/a/cache", want: true},
+		{pattern: "projects/*/cache", path: "projects/a/b/cache", want: false},
+		{pattern: "**/vendor/**", path: "vendor", want: true},
+		{pattern: "**/vendor/**", path: "a/b/vendor/c/d.go", want: true},
+		{pattern: "**/vendor/**", path: "a/vendors/c", want: false},
+		{pattern: "**/*.log", path: "a/b/c.log", want: true},
+		{pattern: "a/**/c", path: "a/c", want: true},
+		{pattern: "a/**/c", path: "a/b/b/c", want: true},
+		{pattern: "a/**/c", path: "a/b/b/c/d", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			got := matchSegments(strings.Split(tt.pattern, "/"), strings.Split(tt.path, "/"))
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_matchGlob(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		globs    []string
+		want     bool
+	}{
+		{
+			name:     "star",
+			filename: "backup-2025.tar.gz",
+			globs:    []string{"backup-*.tar.gz"},
+			want:     true,
+		},
+		{
+			name:     "question mark",
+			filename: "session_1234.tmp",
+			globs:    []string{"session_????.tmp"},
+			want:     true,
+		},
+		{
+			name:     "question mark, wrong length",
+			filename: "session_123.tmp",
+			globs:    []string{"session_????.tmp"},
+			want:     false,
+		},
+		{
+			name:     "character class",
+			filename: "debug-7.log",
+			globs:    []string{"debug-[0-9].log"},
+			want:     true,
+		},
+		{
+			name:     "negated character class",
+			filename: "debug-7.log",
+			globs:    []string{"debug-[^0-9].log"},
+			want:     false,
+		},
+		{
+			name:     "second glob matches",
+			filename: "cache.db",
+			globs:    []string{"*.log", "cache.*"},
+			want:     true,
+		},
+		{
+			name:     "glob must match the whole name",
+			filename: "old-backup.zip.part",
+			globs:    []string{"*.zip"},
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchGlob(tt.filename, tt.globs))
+		})
+	}
+}
+
 func Test_match(t *testing.T) {
 	tests := []struct {
 		name     string
 		filename string
-		names    map[string]bool
+		pattern  string
 		sep      string
 		want     bool
 	}{
 		{
 			name:     "exact match, no separator",
 			filename: "document.pdf",
-			names:    map[string]bool{"document.pdf": true},
+			pattern:  "document.pdf",
 			sep:      "",
 			want:     true,
 		},
 		{
 			name:     "no match, no separator",
 			filename: "other.txt",
-			names:    map[string]bool{"document.pdf": true},
+			pattern:  "document.pdf",
 			sep:      "",
 			want:     false,
 		},
 		{
 			name:     "partial match with dash",
 			filename: "66f3c59b27ea50223262041-5f8033af82716c6a4406628341d86046.pdf",
-			names:    map[string]bool{"66f3c59b27ea50223262041": true},
+			pattern:  "66f3c59b27ea50223262041",
 			sep:      "-",
 			want:     true,
 		},
 		{
 			name:     "partial match in second part",
 			filename: "prefix-5f8033af82716c6a4406628341d86046.pdf",
-			names:    map[string]bool{"5f8033af82716c6a4406628341d86046": true},
+			pattern:  "5f8033af82716c6a4406628341d86046",
 			sep:      "-",
 			want:     true,
 		},
 		{
 			name:     "no match with separator",
 			filename: "wrong-hash-file.pdf",
-			names:    map[string]bool{"correct-hash": true},
+			pattern:  "correct-hash",
 			sep:      "-",
 			want:     false,
 		},
@@ -270,13 +1446,45 @@ func Test_match(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := match(tt.filename, tt.names, tt.sep)
+			m := newMatcher([]conf.Rule{{Mode: conf.MatchExact, Pattern: tt.pattern, Sep: tt.sep}})
+
+			_, got := m.find(tt.filename)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
-func createFiles(t *testing.T, baseDir string, filesPathAndSize map[string]int64) {
+func Test_matcherFind(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		filename string
+		want     string
+	}{
+		{name: "separator groups", patterns: []string{"sep=_:a", "sep=-:c"}, filename: "a_b-c.txt", want: "a"},
+		{name: "separator groups reversed", patterns: []string{"sep=-:c", "sep=_:a"}, filename: "a_b-c.txt", want: "c"},
+		{name: "parts of one group", patterns: []string{"sep=_:b", "sep=_:a"}, filename: "a_b.txt", want: "b"},
+		{name: "glob before exact", patterns: []string{"glob:*.txt", "a.txt"}, filename: "a.txt", want: "*.txt"},
+		{name: "exact before regex", patterns: []string{"a.txt", `re:^a`}, filename: "a.txt", want: "a.txt"},
+		{name: "later glob", patterns: []string{"b.txt", "glob:*.log", "glob:a*"}, filename: "a.txt", want: "a*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := conf.New([]string{"/"}, tt.patterns)
+			assert.NoError(t, err)
+
+			// Exact rules sit in maps, repeat to catch a random order.
+			for range 20 {
+				got, ok := newSelector(cfg).include.find(tt.filename)
+				assert.True(t, ok)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func createFiles(t testing.TB, baseDir string, filesPathAndSize map[string]int64) {
 	t.Helper()
 
 	for fPath, size := range filesPathAndSize {
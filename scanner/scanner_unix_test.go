@@ -0,0 +1,136 @@
+//go:build unix
+
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/figurecode/files-remover/conf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanDirTypes(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	createFiles(t, tmpDir, map[string]int64{
+		"data.tmp":       100,
+		"empty.tmp":      0,
+		"sub/target.txt": 10,
+	})
+
+	assert.NoError(t, os.Symlink(filepath.Join(tmpDir, "sub/target.txt"), filepath.Join(tmpDir, "link.tmp")))
+	assert.NoError(t, os.Symlink(filepath.Join(tmpDir, "missing.txt"), filepath.Join(tmpDir, "sub/broken.tmp")))
+	assert.NoError(t, syscall.Mkfifo(filepath.Join(tmpDir, "pipe.tmp"), 0o644))
+
+	l, err := net.Listen("unix", filepath.Join(tmpDir, "sock.tmp"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	tests := []struct {
+		types string
+		want  []string
+	}{
+		{types: "", want: []string{"data.tmp", "empty.tmp", "link.tmp", "sub/broken.tmp", "pipe.tmp", "sock.tmp"}},
+		{types: "f", want: []string{"data.tmp", "empty.tmp"}},
+		{types: "empty", want: []string{"empty.tmp"}},
+		{types: "l", want: []string{"link.tmp", "sub/broken.tmp"}},
+		{types: "broken-link", want: []string{"sub/broken.tmp"}},
+		{types: "p,s", want: []string{"pipe.tmp", "sock.tmp"}},
+		{types: "empty,broken-link", want: []string{"empty.tmp", "sub/broken.tmp"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.types, func(t *testing.T) {
+			cfg, err := conf.New([]string{tmpDir}, []string{"glob:*.tmp"}, conf.WithTypes(tt.types))
+			assert.NoError(t, err)
+
+			res, err := ScanDir(t.Context(), cfg)
+			assert.NoError(t, err)
+
+			got := make([]string, 0, len(res.Files))
+			for path := range res.Files {
+				rel, _ := filepath.Rel(tmpDir, path)
+				got = append(got, rel)
+			}
+
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func TestScanDirOnError(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+
+	tmpDir := t.TempDir()
+
+	createFiles(t, tmpDir, map[string]int64{
+		"a.tmp":        100,
+		"locked/b.tmp": 200,
+		"open/c.tmp":   300,
+	})
+
+	locked := filepath.Join(tmpDir, "locked")
+	assert.NoError(t, os.Chmod(locked, 0))
+	t.Cleanup(func() { _ = os.Chmod(locked, 0o755) })
+
+	t.Run("abort", func(t *testing.T) {
+		cfg, err := conf.New([]string{tmpDir}, []string{"glob:*.tmp"})
+		assert.NoError(t, err)
+
+		_, err = ScanDir(t.Context(), cfg)
+		assert.ErrorIs(t, err, os.ErrPermission)
+	})
+
+	for _, workers := range []int{1, 4} {
+		for _, policy := range []string{"skip", "warn"} {
+			t.Run(fmt.Sprintf("%s workers=%d", policy, workers), func(t *testing.T) {
+				errStream := &bytes.Buffer{}
+
+				cfg, err := conf.New(
+					[]string{tmpDir},
+					[]string{"glob:*.tmp"},
+					conf.WithOnError(policy),
+					conf.WithWorkers(workers),
+					conf.WithErrStream(errStream),
+				)
+				assert.NoError(t, err)
+
+				res, err := ScanDir(t.Context(), cfg)
+				assert.NoError(t, err)
+
+				assert.Len(t, res.Files, 2)
+				assert.Equal(t, []SkippedPath{{Path: locked, Err: syscall.EACCES}}, res.Skipped)
+				assert.Equal(t, policy == "warn", strings.Contains(errStream.String(), "Warning: skipped "+locked))
+			})
+		}
+	}
+}
+
+func TestScanOwner(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	createFiles(t, tmpDir, map[string]int64{"a.tmp": 1})
+
+	want := fmt.Sprint(os.Getuid())
+	if u, err := user.Current(); err == nil {
+		want = u.Username
+	}
+
+	cfg, err := conf.New([]string{tmpDir}, []string{"a.tmp"})
+	assert.NoError(t, err)
+
+	for f, err := range Scan(t.Context(), cfg) {
+		assert.NoError(t, err)
+		assert.Equal(t, want, f.Owner)
+	}
+}